@@ -3,24 +3,44 @@ package main
 import (
 	"context"
 	"fmt"
+	"github.com/KirillKhitev/carat_export/internal/api"
+	"github.com/KirillKhitev/carat_export/internal/catalog"
 	"github.com/KirillKhitev/carat_export/internal/config"
 	"github.com/KirillKhitev/carat_export/internal/controller"
+	"github.com/KirillKhitev/carat_export/internal/export"
+	"github.com/KirillKhitev/carat_export/internal/leads"
 	"github.com/KirillKhitev/carat_export/internal/logger"
+	"github.com/KirillKhitev/carat_export/internal/orders"
+	"github.com/KirillKhitev/carat_export/internal/report"
+	"github.com/KirillKhitev/carat_export/internal/snapshot"
+	"github.com/KirillKhitev/carat_export/internal/wildberries"
 	"github.com/sirupsen/logrus"
 	"net/http"
 	"os"
 	"os/signal"
+	"slices"
 	"syscall"
 )
 
+// imagesRoute путь HTTP сервера, по которому отдаются изображения товаров.
+const imagesRoute = "/images/"
+
+func init() {
+	config.ReserveRoute(imagesRoute, "изображения товаров")
+}
+
 type app struct {
 	c      *controller.Controller
+	orders *orders.Importer
+	leads  *leads.Poller
 	server http.Server
 }
 
 func newApp() *app {
 	instance := &app{
-		c: controller.NewController(),
+		c:      controller.NewController(),
+		orders: orders.NewImporter(),
+		leads:  leads.NewPoller(),
 	}
 
 	return instance
@@ -31,20 +51,39 @@ func (a *app) StartFileServer() {
 	fs := http.FileServer(http.Dir(config.Config.ImagesPath))
 
 	mux := http.NewServeMux()
-	mux.Handle("/images/", http.StripPrefix("/images/", fs))
-	mux.HandleFunc("/products.xml", func(w http.ResponseWriter, r *http.Request) {
-		logger.Log.Log(logrus.InfoLevel, "Пришли за файлом авито:")
-
-		s := ""
-		for h, values := range r.Header {
-			s += h + ":\r\n"
-			for i, v := range values {
-				s += fmt.Sprintf("\t%d: %s", i, v) + "\r\n"
-			}
+	mux.Handle(imagesRoute, http.StripPrefix(imagesRoute, fs))
+	for _, exporter := range export.Enabled() {
+		if exporter.ServePath() != "" {
+			mux.HandleFunc(exporter.ServePath(), exportHandler(exporter))
 		}
+	}
 
-		logger.Log.Log(logrus.InfoLevel, s)
-		http.ServeFile(w, r, config.Config.AvitoFilePath)
+	if siteParams := config.Config.Site; siteParams.Enabled() {
+		mux.Handle(siteParams.URL, http.StripPrefix(siteParams.URL, http.FileServer(http.Dir(siteParams.Dir))))
+	}
+
+	mux.Handle(api.Prefix, api.NewCatalog(snapshot.Path()))
+
+	mux.HandleFunc("/report.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		http.ServeFile(w, r, report.JSONPath())
+	})
+	mux.HandleFunc("/report.csv", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		http.ServeFile(w, r, report.CSVPath())
+	})
+	mux.HandleFunc("/"+catalog.CSVFileName, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		http.ServeFile(w, r, catalog.CSVPath())
+	})
+	mux.HandleFunc("/"+catalog.XLSXFileName, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+catalog.XLSXFileName+`"`)
+		http.ServeFile(w, r, catalog.XLSXPath())
+	})
+	mux.HandleFunc("/"+wildberries.UnmatchedFileName, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		http.ServeFile(w, r, wildberries.UnmatchedPath())
 	})
 
 	a.server = http.Server{
@@ -59,26 +98,66 @@ func (a *app) StartFileServer() {
 	}
 }
 
+// exportHandler отдает файл выгрузки и логирует заголовки запроса.
+func exportHandler(exporter export.Exporter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Log.Logf(logrus.InfoLevel, "Пришли за файлом выгрузки %s:", exporter.Name())
+
+		s := ""
+		for h, values := range r.Header {
+			s += h + ":\r\n"
+			for i, v := range values {
+				s += fmt.Sprintf("\t%d: %s", i, v) + "\r\n"
+			}
+		}
+
+		logger.Log.Log(logrus.InfoLevel, s)
+		http.ServeFile(w, r, exporter.FilePath())
+	}
+}
+
 func (a *app) StartController(ctx context.Context) {
 	a.c.Start(ctx)
 }
 
-// Bootstrap создает необходимые папки
+// StartOrdersImporter запускает загрузку заказов Avito в МойСклад, если она настроена.
+func (a *app) StartOrdersImporter(ctx context.Context) {
+	a.orders.Start(ctx)
+}
+
+// StartLeadsPoller запускает обработку чатов мессенджера Avito, если она настроена.
+func (a *app) StartLeadsPoller(ctx context.Context) {
+	a.leads.Start(ctx)
+}
+
+// Bootstrap проверяет форматы выгрузки из настроек и создает необходимые папки
 func (a *app) Bootstrap() error {
-	if _, err := os.Stat(config.Config.ImagesPath); err == nil {
-		return nil
+	for _, format := range config.Config.Exporters {
+		if !slices.Contains(export.Formats(), format) {
+			return fmt.Errorf("неизвестный формат выгрузки: %s", format)
+		}
 	}
 
-	if err := os.Mkdir(config.Config.ImagesPath, 0755); err != nil {
-		return err
+	dirs := []string{
+		config.Config.ImagesPath,
+		config.Config.LogDir,
+		config.Config.ReportDir,
+		config.Config.HistoryDir,
+		config.Config.Site.Dir,
 	}
 
-	if _, err := os.Stat(config.Config.LogDir); err == nil {
-		return nil
-	}
+	for _, dir := range dirs {
+		if dir == "" {
+			continue
+		}
 
-	if err := os.Mkdir(config.Config.LogDir, 0644); err != nil {
-		return err
+		if _, err := os.Stat(dir); err == nil {
+			continue
+		}
+
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -89,6 +168,14 @@ func (a *app) Close() error {
 		return err
 	}
 
+	if err := a.orders.Close(); err != nil {
+		return err
+	}
+
+	if err := a.leads.Close(); err != nil {
+		return err
+	}
+
 	if err := a.c.Close(); err != nil {
 		return err
 	}
@@ -44,6 +44,8 @@ func run() error {
 
 	go appInstance.StartFileServer()
 	go appInstance.StartController(ctx)
+	go appInstance.StartOrdersImporter(ctx)
+	go appInstance.StartLeadsPoller(ctx)
 
 	return appInstance.CatchTerminateSignal()
 }
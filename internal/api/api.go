@@ -0,0 +1,237 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/KirillKhitev/carat_export/internal/config"
+	"github.com/KirillKhitev/carat_export/internal/logger"
+	"github.com/KirillKhitev/carat_export/internal/snapshot"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Prefix путь API каталога на HTTP сервере.
+const Prefix = "/api/"
+
+func init() {
+	config.ReserveRoute(Prefix, "API каталога")
+}
+
+// Ограничения размера страницы списка товаров.
+const (
+	defaultLimit = 50
+	maxLimit     = 500
+)
+
+// Catalog API только для чтения по снимку последней успешной выгрузки. Снимок перечитывается
+// с диска, когда файл меняется.
+type Catalog struct {
+	m        *sync.Mutex
+	path     string
+	modTime  time.Time
+	snapshot *snapshot.Snapshot
+}
+
+func NewCatalog(path string) *Catalog {
+	return &Catalog{
+		m:    &sync.Mutex{},
+		path: path,
+	}
+}
+
+// ProductsResponse страница списка товаров.
+type ProductsResponse struct {
+	UpdatedAt time.Time          `json:"updated_at"`
+	Total     int                `json:"total"`
+	Page      int                `json:"page"`
+	Limit     int                `json:"limit"`
+	Items     []snapshot.Product `json:"items"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// Filter условия отбора товаров.
+type Filter struct {
+	Folder   string
+	MinPrice int
+	MaxPrice int
+	Query    string
+}
+
+// Match проверяет, подходит ли товар под условия. Группа учитывается вместе с подгруппами,
+// поиск ведется без учета регистра по названию, артикулу и группе.
+func (f Filter) Match(p snapshot.Product) bool {
+	if f.Folder != "" && p.Folder != f.Folder && !strings.HasPrefix(p.Folder, f.Folder+"/") {
+		return false
+	}
+
+	if f.MinPrice > 0 && p.Price < f.MinPrice {
+		return false
+	}
+
+	if f.MaxPrice > 0 && p.Price > f.MaxPrice {
+		return false
+	}
+
+	if f.Query != "" {
+		query := strings.ToLower(f.Query)
+		text := strings.ToLower(p.Name + " " + p.Article + " " + p.Folder)
+
+		if !strings.Contains(text, query) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ServeHTTP обрабатывает запросы GET /api/products и GET /api/products/{id}.
+func (c *Catalog) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "поддерживается только GET")
+		return
+	}
+
+	s, err := c.load()
+	if errors.Is(err, os.ErrNotExist) {
+		writeError(w, http.StatusServiceUnavailable, "каталог еще не сформирован")
+		return
+	}
+
+	if err != nil {
+		logger.Log.WithFields(logrus.Fields{
+			"error": err,
+		}).Log(logrus.ErrorLevel, "Ошибка чтения снимка каталога")
+
+		writeError(w, http.StatusInternalServerError, "ошибка чтения каталога")
+		return
+	}
+
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, Prefix), "/")
+
+	switch {
+	case path == "products":
+		c.list(w, r, s)
+	case strings.HasPrefix(path, "products/"):
+		c.product(w, s, strings.TrimPrefix(path, "products/"))
+	default:
+		writeError(w, http.StatusNotFound, "неизвестный метод")
+	}
+}
+
+// list отдает страницу товаров с учетом фильтров.
+func (c *Catalog) list(w http.ResponseWriter, r *http.Request, s *snapshot.Snapshot) {
+	query := r.URL.Query()
+
+	filter := Filter{
+		Folder: query.Get("folder"),
+		Query:  strings.TrimSpace(query.Get("q")),
+	}
+
+	page, limit := 1, defaultLimit
+
+	params := []struct {
+		name  string
+		value *int
+	}{
+		{"min_price", &filter.MinPrice},
+		{"max_price", &filter.MaxPrice},
+		{"page", &page},
+		{"limit", &limit},
+	}
+
+	for _, param := range params {
+		raw := query.Get(param.name)
+		if raw == "" {
+			continue
+		}
+
+		value, err := strconv.Atoi(raw)
+		if err != nil || value < 0 {
+			writeError(w, http.StatusBadRequest, "неверное значение параметра "+param.name)
+			return
+		}
+
+		*param.value = value
+	}
+
+	page = max(page, 1)
+	limit = min(max(limit, 1), maxLimit)
+
+	matched := make([]snapshot.Product, 0)
+	for _, p := range s.Products {
+		if filter.Match(p) {
+			matched = append(matched, p)
+		}
+	}
+
+	start := min((page-1)*limit, len(matched))
+	end := min(start+limit, len(matched))
+
+	writeJSON(w, http.StatusOK, ProductsResponse{
+		UpdatedAt: s.CreatedAt,
+		Total:     len(matched),
+		Page:      page,
+		Limit:     limit,
+		Items:     matched[start:end],
+	})
+}
+
+// product отдает товар по ID или артикулу.
+func (c *Catalog) product(w http.ResponseWriter, s *snapshot.Snapshot, id string) {
+	for _, p := range s.Products {
+		if p.ID == id || (p.Article != "" && p.Article == id) {
+			writeJSON(w, http.StatusOK, p)
+			return
+		}
+	}
+
+	writeError(w, http.StatusNotFound, "товар не найден")
+}
+
+// load возвращает снимок каталога, перечитывая файл, если он изменился.
+func (c *Catalog) load() (*snapshot.Snapshot, error) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	info, err := os.Stat(c.path)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.snapshot != nil && info.ModTime().Equal(c.modTime) {
+		return c.snapshot, nil
+	}
+
+	s, err := snapshot.Load(c.path)
+	if err != nil {
+		return nil, err
+	}
+
+	c.snapshot = s
+	c.modTime = info.ModTime()
+
+	return s, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.Log.WithFields(logrus.Fields{
+			"error": err,
+		}).Log(logrus.ErrorLevel, "Ошибка отправки ответа API каталога")
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, errorResponse{Error: message})
+}
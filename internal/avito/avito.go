@@ -2,16 +2,23 @@ package avito
 
 import (
 	"encoding/xml"
-	"github.com/KirillKhitev/carat_export/internal/config"
+	"github.com/KirillKhitev/carat_export/internal/fileutil"
 	"github.com/KirillKhitev/carat_export/internal/logger"
+	"github.com/KirillKhitev/carat_export/internal/timeutil"
 	"github.com/sirupsen/logrus"
-	"os"
+	"io"
+	"time"
 )
 
+// DateFormat формат даты и времени, который принимает Avito. Avito ожидает московское время.
+const DateFormat = "2006-01-02T15:04:05-07:00"
+
 type Product struct {
 	XMLName     xml.Name           `xml:"Ad"`
 	ID          string             `xml:"Id"`
 	AvitoId     string             `xml:"AvitoId,omitempty"`
+	DateBegin   string             `xml:"DateBegin,omitempty"`
+	DateEnd     string             `xml:"DateEnd,omitempty"`
 	Title       string             `xml:"Title"`
 	Description ProductDescription `xml:"Description"`
 	Images      Images             `xml:"Images"`
@@ -22,6 +29,14 @@ type Product struct {
 	Condition   string             `xml:"Condition"`
 	Price       int                `xml:"Price"`
 	VideoURL    string             `xml:"VideoURL"`
+
+	ContactPhone  string `xml:"ContactPhone,omitempty"`
+	ManagerName   string `xml:"ManagerName,omitempty"`
+	ContactMethod string `xml:"ContactMethod,omitempty"`
+	ListingFee    string `xml:"ListingFee,omitempty"`
+	AdStatus      string `xml:"AdStatus,omitempty"`
+
+	Extra []Element `xml:",any"`
 }
 
 type ProductDescription struct {
@@ -35,38 +50,54 @@ type Image struct {
 	Url string `xml:"url,attr"`
 }
 
-type ProductsExport struct {
-	XMLName       xml.Name  `xml:"Ads"`
-	FormatVersion int       `xml:"formatVersion,attr"`
-	Target        string    `xml:"target,attr"`
-	Products      []Product `xml:"Ad"`
+// FormatDate форматирует дату для полей DateBegin и DateEnd в московском времени.
+func FormatDate(t time.Time) string {
+	return t.In(timeutil.Moscow).Format(DateFormat)
 }
 
-func CreateAutoloadFile(products []Product) error {
-	logger.Log.Logln(logrus.InfoLevel, "Сохраняем товары в файл авито")
+// CreateAutoloadFile потоково записывает файл автозагрузки во временный файл и атомарно
+// подменяет им файл выгрузки, чтобы Avito не забрал недописанный файл.
+func CreateAutoloadFile(path string, products []Product) error {
+	logger.Log.Logf(logrus.InfoLevel, "Сохраняем товары в файл авито %s", path)
 	logger.Log.WithFields(logrus.Fields{
 		"products": products,
 	}).Logln(logrus.DebugLevel, "Подготовленный список товаров")
 
-	f, err := os.Create(config.Config.AvitoFilePath)
-	defer f.Close()
+	return fileutil.WriteAtomic(path, func(w io.Writer) error {
+		return encodeProducts(w, products)
+	})
+}
 
-	if err != nil {
+// encodeProducts пишет объявления в XML по одному, не собирая весь файл в памяти.
+func encodeProducts(w io.Writer, products []Product) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
 		return err
 	}
 
-	pe := ProductsExport{}
-	pe.FormatVersion = 3
-	pe.Target = "Avito.ru"
-	pe.Products = products
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "   ")
+
+	root := xml.StartElement{
+		Name: xml.Name{Local: "Ads"},
+		Attr: []xml.Attr{
+			{Name: xml.Name{Local: "formatVersion"}, Value: "3"},
+			{Name: xml.Name{Local: "target"}, Value: "Avito.ru"},
+		},
+	}
 
-	var data []byte
-	data, err = xml.MarshalIndent(pe, "", "   ")
-	if err != nil {
+	if err := enc.EncodeToken(root); err != nil {
 		return err
 	}
 
-	f.Write(data)
+	for _, p := range products {
+		if err := enc.Encode(p); err != nil {
+			return err
+		}
+	}
+
+	if err := enc.EncodeToken(root.End()); err != nil {
+		return err
+	}
 
-	return nil
+	return enc.Flush()
 }
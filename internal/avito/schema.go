@@ -0,0 +1,108 @@
+package avito
+
+import (
+	"encoding/xml"
+	"github.com/KirillKhitev/carat_export/internal/config"
+	"sync"
+)
+
+// Element дополнительный элемент объявления, который выводится в XML рядом с базовыми полями.
+type Element struct {
+	XMLName xml.Name
+	Value   string `xml:",chardata"`
+}
+
+// Schema описывает дополнительные элементы объявлений категории Avito.
+// Пустой GoodsType означает, что схема подходит для любого вида товара категории.
+type Schema = config.CategorySchema
+
+// Field дополнительный элемент схемы категории.
+type Field = config.CategoryField
+
+var (
+	schemasMutex = &sync.RWMutex{}
+	schemas      = []Schema{
+		{
+			Category: "Одежда, обувь, аксессуары",
+			Fields: []Field{
+				{Name: "Size", Attribute: "Размер", Required: true},
+				{Name: "Brand", Attribute: "Бренд"},
+			},
+		},
+		{
+			Category:  "Коллекционирование",
+			GoodsType: "Монеты",
+			Fields: []Field{
+				{Name: "Material", Attribute: "Материал"},
+				{Name: "Year", Attribute: "Год выпуска"},
+			},
+		},
+		{
+			Category: "Книги и журналы",
+			Fields: []Field{
+				{Name: "Author", Attribute: "Автор"},
+			},
+		},
+	}
+)
+
+// RegisterSchema добавляет схему категории. Схема, зарегистрированная позже, имеет приоритет
+// над встроенной для той же категории и вида товара.
+func RegisterSchema(s Schema) {
+	schemasMutex.Lock()
+	defer schemasMutex.Unlock()
+
+	schemas = append(schemas, s)
+}
+
+// SchemaFor возвращает схему для категории и вида товара. Схемы из настроек имеют приоритет над
+// зарегистрированными, схема вида товара - над схемой всей категории.
+func SchemaFor(category, goodsType string) (Schema, bool) {
+	schemasMutex.RLock()
+	defer schemasMutex.RUnlock()
+
+	all := append(append([]Schema{}, schemas...), config.Config.Schemas...)
+
+	for _, wantGoodsType := range []string{goodsType, ""} {
+		for i := len(all) - 1; i >= 0; i-- {
+			if all[i].Category == category && all[i].GoodsType == wantGoodsType {
+				return all[i], true
+			}
+		}
+	}
+
+	return Schema{}, false
+}
+
+// ApplySchema заполняет дополнительные элементы объявления по схеме его категории
+// из доп. полей товара МойСклад.
+func ApplySchema(p *Product, attributes map[string]string) {
+	schema, ok := SchemaFor(p.Category, p.GoodsType)
+	if !ok {
+		return
+	}
+
+	for _, field := range schema.Fields {
+		value := attributes[field.Attribute]
+		if value == "" {
+			value = field.Default
+		}
+
+		if value == "" {
+			continue
+		}
+
+		p.Extra = append(p.Extra, Element{XMLName: xml.Name{Local: field.Name}, Value: value})
+	}
+}
+
+// extraValue возвращает значение дополнительного элемента объявления.
+func extraValue(p Product, name string) string {
+	for _, e := range p.Extra {
+		if e.XMLName.Local == name {
+			return e.Value
+		}
+	}
+
+	return ""
+}
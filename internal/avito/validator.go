@@ -0,0 +1,213 @@
+package avito
+
+import (
+	"fmt"
+	"github.com/KirillKhitev/carat_export/internal/textutil"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Ограничения автозагрузки Avito.
+const (
+	MaxTitleLength       = 50
+	MaxDescriptionLength = 7500
+	MaxImages            = 10
+)
+
+// unsupportedImageExtensions форматы изображений, которые Avito не принимает.
+var unsupportedImageExtensions = []string{".webp", ".bmp", ".tif", ".tiff", ".heic", ".svg"}
+
+// videoHosts хосты, ссылки на которые Avito принимает в VideoURL.
+var videoHosts = []string{"youtube.com", "www.youtube.com", "m.youtube.com", "youtu.be"}
+
+// requiredFields обязательные поля объявления в зависимости от категории.
+var requiredFields = map[string][]string{
+	"Коллекционирование": {"GoodsType", "AdType", "Condition"},
+}
+
+// Issue проблема, найденная при проверке объявления.
+type Issue struct {
+	ID      string
+	Field   string
+	Message string
+	// Fixed - объявление исправлено и осталось в выгрузке, иначе оно исключено.
+	Fixed bool
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("%s: %s", i.Field, i.Message)
+}
+
+// Validate проверяет объявления по правилам автозагрузки Avito. Исправимые ошибки исправляются,
+// объявления с неисправимыми ошибками исключаются из результата.
+func Validate(products []Product) ([]Product, []Issue) {
+	result := make([]Product, 0, len(products))
+	issues := make([]Issue, 0)
+	ids := make(map[string]struct{}, len(products))
+
+	for _, p := range products {
+		productIssues := validateProduct(&p)
+
+		if _, ok := ids[p.ID]; ok {
+			productIssues = append(productIssues, Issue{ID: p.ID, Field: "Id", Message: "повторяющийся идентификатор объявления"})
+		}
+
+		issues = append(issues, productIssues...)
+
+		if slices.ContainsFunc(productIssues, func(i Issue) bool { return !i.Fixed }) {
+			continue
+		}
+
+		ids[p.ID] = struct{}{}
+		result = append(result, p)
+	}
+
+	return result, issues
+}
+
+// validateProduct проверяет одно объявление, исправляя то, что можно исправить.
+func validateProduct(p *Product) []Issue {
+	issues := make([]Issue, 0)
+
+	fail := func(field, message string) {
+		issues = append(issues, Issue{ID: p.ID, Field: field, Message: message})
+	}
+
+	fix := func(field, message string) {
+		issues = append(issues, Issue{ID: p.ID, Field: field, Message: message, Fixed: true})
+	}
+
+	if p.ID == "" {
+		fail("Id", "пустой идентификатор объявления")
+	}
+
+	if strings.TrimSpace(p.Title) == "" {
+		fail("Title", "пустое название")
+	} else if utf8.RuneCountInString(p.Title) > MaxTitleLength {
+		p.Title = textutil.Truncate(p.Title, MaxTitleLength)
+		fix("Title", fmt.Sprintf("название обрезано до %d символов", MaxTitleLength))
+	}
+
+	if strings.TrimSpace(p.Description.Text) == "" {
+		fail("Description", "пустое описание")
+	} else if utf8.RuneCountInString(p.Description.Text) > MaxDescriptionLength {
+		p.Description.Text = truncateHTML(p.Description.Text, MaxDescriptionLength)
+		fix("Description", fmt.Sprintf("описание обрезано до %d символов", MaxDescriptionLength))
+	}
+
+	if p.Price <= 0 {
+		fail("Price", "не указана цена")
+	}
+
+	images := slices.DeleteFunc(slices.Clone(p.Images.Image), func(img Image) bool {
+		return !supportedImage(img.Url)
+	})
+
+	if len(images) < len(p.Images.Image) {
+		fix("Images", "удалены изображения в неподдерживаемом формате")
+	}
+
+	if len(images) > MaxImages {
+		images = images[:MaxImages]
+		fix("Images", fmt.Sprintf("оставлены первые %d изображений", MaxImages))
+	}
+
+	p.Images.Image = images
+
+	if len(p.Images.Image) == 0 {
+		fail("Images", "нет изображений")
+	}
+
+	if p.VideoURL != "" && !supportedVideo(p.VideoURL) {
+		p.VideoURL = ""
+		fix("VideoURL", "ссылка на видео удалена, поддерживаются только ссылки на YouTube")
+	}
+
+	if p.DateBegin != "" && p.DateEnd != "" {
+		begin, errBegin := time.Parse(DateFormat, p.DateBegin)
+		end, errEnd := time.Parse(DateFormat, p.DateEnd)
+
+		if errBegin == nil && errEnd == nil && end.Before(begin) {
+			fail("DateEnd", "дата окончания публикации раньше даты начала")
+		}
+	}
+
+	if p.Address == "" {
+		fail("Address", "не указан адрес")
+	}
+
+	if p.Category == "" {
+		fail("Category", "не указана категория")
+	}
+
+	required := requiredFields[p.Category]
+
+	if schema, ok := SchemaFor(p.Category, p.GoodsType); ok {
+		for _, field := range schema.Fields {
+			if field.Required {
+				required = append(slices.Clip(required), field.Name)
+			}
+		}
+	}
+
+	for _, field := range required {
+		if fieldValue(*p, field) == "" {
+			fail(field, fmt.Sprintf("обязательное поле для категории «%s»", p.Category))
+		}
+	}
+
+	return issues
+}
+
+// fieldValue возвращает значение поля объявления по имени XML элемента.
+func fieldValue(p Product, field string) string {
+	switch field {
+	case "GoodsType":
+		return p.GoodsType
+	case "AdType":
+		return p.AdType
+	case "Condition":
+		return p.Condition
+	case "Address":
+		return p.Address
+	}
+
+	return extraValue(p, field)
+}
+
+// supportedImage проверяет формат изображения по расширению файла.
+func supportedImage(rawUrl string) bool {
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return false
+	}
+
+	ext := strings.ToLower(path.Ext(u.Path))
+
+	return !slices.Contains(unsupportedImageExtensions, ext)
+}
+
+// supportedVideo проверяет, что ссылка на видео ведет на поддерживаемый Avito хост.
+func supportedVideo(rawUrl string) bool {
+	u, err := url.Parse(rawUrl)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+
+	return slices.Contains(videoHosts, strings.ToLower(u.Hostname()))
+}
+
+// truncateHTML обрезает HTML описание до limit символов, не оставляя на конце оборванный тег.
+func truncateHTML(s string, limit int) string {
+	s = textutil.Truncate(s, limit)
+
+	if i := strings.LastIndex(s, "<"); i > strings.LastIndex(s, ">") {
+		s = strings.TrimSpace(s[:i])
+	}
+
+	return s
+}
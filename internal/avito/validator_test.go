@@ -0,0 +1,211 @@
+package avito
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func validAd() Product {
+	return Product{
+		ID:          "1",
+		Title:       "Монета Георгий Победоносец",
+		Description: ProductDescription{Text: "<p>Серебряная монета</p>"},
+		Images:      Images{Image: []Image{{Url: "https://example.com/1.jpg"}}},
+		Address:     "Москва, Тверская, 1",
+		Category:    "Коллекционирование",
+		GoodsType:   "Монеты",
+		AdType:      "Товар приобретен на продажу",
+		Condition:   "Новое",
+		Price:       1500,
+	}
+}
+
+func TestValidateProduct(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(p *Product)
+		// field поле с ошибкой, пустое - объявление без замечаний.
+		field string
+		fixed bool
+		check func(t *testing.T, p Product)
+	}{
+		{
+			name:   "valid ad",
+			modify: func(p *Product) {},
+		},
+		{
+			name:   "empty id",
+			modify: func(p *Product) { p.ID = "" },
+			field:  "Id",
+		},
+		{
+			name:   "blank title",
+			modify: func(p *Product) { p.Title = "  " },
+			field:  "Title",
+		},
+		{
+			name:   "long title is truncated",
+			modify: func(p *Product) { p.Title = strings.Repeat("Ж", MaxTitleLength+10) },
+			field:  "Title",
+			fixed:  true,
+			check: func(t *testing.T, p Product) {
+				if n := utf8.RuneCountInString(p.Title); n > MaxTitleLength {
+					t.Errorf("title length = %d", n)
+				}
+			},
+		},
+		{
+			name:   "empty description",
+			modify: func(p *Product) { p.Description.Text = "" },
+			field:  "Description",
+		},
+		{
+			name: "long description is truncated without broken tag",
+			modify: func(p *Product) {
+				p.Description.Text = strings.Repeat("ж", MaxDescriptionLength-3) + "<strong>конец</strong>"
+			},
+			field: "Description",
+			fixed: true,
+			check: func(t *testing.T, p Product) {
+				if n := utf8.RuneCountInString(p.Description.Text); n > MaxDescriptionLength {
+					t.Errorf("description length = %d", n)
+				}
+
+				if strings.Contains(p.Description.Text, "<") {
+					t.Errorf("description ends with broken tag: %q", p.Description.Text[len(p.Description.Text)-10:])
+				}
+			},
+		},
+		{
+			name:   "no price",
+			modify: func(p *Product) { p.Price = 0 },
+			field:  "Price",
+		},
+		{
+			name: "unsupported images are removed",
+			modify: func(p *Product) {
+				p.Images.Image = append(p.Images.Image, Image{Url: "https://example.com/2.WEBP"})
+			},
+			field: "Images",
+			fixed: true,
+			check: func(t *testing.T, p Product) {
+				if len(p.Images.Image) != 1 {
+					t.Errorf("images = %v", p.Images.Image)
+				}
+			},
+		},
+		{
+			name:   "only unsupported images",
+			modify: func(p *Product) { p.Images.Image = []Image{{Url: "https://example.com/1.svg"}} },
+			field:  "Images",
+		},
+		{
+			name: "too many images",
+			modify: func(p *Product) {
+				for len(p.Images.Image) < MaxImages+2 {
+					p.Images.Image = append(p.Images.Image, Image{Url: "https://example.com/n.jpg"})
+				}
+			},
+			field: "Images",
+			fixed: true,
+			check: func(t *testing.T, p Product) {
+				if len(p.Images.Image) != MaxImages {
+					t.Errorf("images = %d", len(p.Images.Image))
+				}
+			},
+		},
+		{
+			name:   "video not on youtube",
+			modify: func(p *Product) { p.VideoURL = "https://vimeo.com/1" },
+			field:  "VideoURL",
+			fixed:  true,
+			check: func(t *testing.T, p Product) {
+				if p.VideoURL != "" {
+					t.Errorf("video = %q", p.VideoURL)
+				}
+			},
+		},
+		{
+			name:   "youtube video",
+			modify: func(p *Product) { p.VideoURL = "https://youtu.be/abc" },
+		},
+		{
+			name: "end date before begin date",
+			modify: func(p *Product) {
+				p.DateBegin = "2024-05-10T00:00:00+03:00"
+				p.DateEnd = "2024-05-01T00:00:00+03:00"
+			},
+			field: "DateEnd",
+		},
+		{
+			name:   "no address",
+			modify: func(p *Product) { p.Address = "" },
+			field:  "Address",
+		},
+		{
+			name:   "required field of category",
+			modify: func(p *Product) { p.Condition = "" },
+			field:  "Condition",
+		},
+		{
+			name: "required field of schema",
+			modify: func(p *Product) {
+				p.Category = "Одежда, обувь, аксессуары"
+				p.GoodsType = "Мужская одежда"
+			},
+			field: "Size",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := validAd()
+			tt.modify(&p)
+
+			issues := validateProduct(&p)
+
+			if tt.field == "" {
+				if len(issues) != 0 {
+					t.Fatalf("issues = %v, want none", issues)
+				}
+
+				return
+			}
+
+			for _, issue := range issues {
+				if issue.Field != tt.field {
+					t.Fatalf("issues = %+v, want issues only in %s", issues, tt.field)
+				}
+			}
+
+			if len(issues) == 0 || issues[len(issues)-1].Fixed != tt.fixed {
+				t.Fatalf("issues = %+v, want issue in %s (fixed: %v)", issues, tt.field, tt.fixed)
+			}
+
+			if tt.check != nil {
+				tt.check(t, p)
+			}
+		})
+	}
+}
+
+func TestValidateExcludesBrokenAndDuplicateAds(t *testing.T) {
+	noPrice := validAd()
+	noPrice.ID = "2"
+	noPrice.Price = 0
+
+	longTitle := validAd()
+	longTitle.ID = "3"
+	longTitle.Title = strings.Repeat("Ж", MaxTitleLength+1)
+
+	result, issues := Validate([]Product{validAd(), noPrice, longTitle, validAd()})
+
+	if len(result) != 2 || result[0].ID != "1" || result[1].ID != "3" {
+		t.Fatalf("result = %+v, want ads 1 and 3", result)
+	}
+
+	if len(issues) != 3 || issues[2].ID != "1" || issues[2].Field != "Id" {
+		t.Fatalf("issues = %+v, want price, title and duplicate id", issues)
+	}
+}
@@ -0,0 +1,88 @@
+package avitoapi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// Типы сообщений в отчете автозагрузки.
+const (
+	MessageError   = "error"
+	MessageWarning = "warning"
+)
+
+const reportItemsPerPage = 200
+
+// AutoloadReport отчет Avito об обработке файла автозагрузки.
+type AutoloadReport struct {
+	ReportId   int    `json:"report_id"`
+	Status     string `json:"status"`
+	StartedAt  string `json:"started_at"`
+	FinishedAt string `json:"finished_at"`
+}
+
+// ReportItem результат обработки одного объявления из файла автозагрузки.
+type ReportItem struct {
+	AdId        string          `json:"ad_id"`
+	AvitoId     int64           `json:"avito_id"`
+	AvitoStatus string          `json:"avito_status"`
+	Url         string          `json:"url"`
+	Messages    []ReportMessage `json:"messages"`
+}
+
+type ReportMessage struct {
+	Type        string `json:"type"`
+	Code        int    `json:"code"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+}
+
+func (m ReportMessage) String() string {
+	if m.Description == "" {
+		return m.Title
+	}
+
+	return fmt.Sprintf("%s: %s", m.Title, m.Description)
+}
+
+type reportItemsResponse struct {
+	Items []ReportItem `json:"items"`
+	Meta  struct {
+		Page    int `json:"page"`
+		Pages   int `json:"pages"`
+		PerPage int `json:"per_page"`
+		Total   int `json:"total"`
+	} `json:"meta"`
+}
+
+// LastCompletedReport получает последний завершенный отчет автозагрузки.
+func (c *Client) LastCompletedReport(ctx context.Context) (AutoloadReport, error) {
+	var result AutoloadReport
+
+	err := c.request(ctx, http.MethodGet, "autoload/v2/reports/last_completed_report", nil, &result)
+
+	return result, err
+}
+
+// ReportItems получает результаты обработки всех объявлений из отчета автозагрузки.
+func (c *Client) ReportItems(ctx context.Context, reportId int) ([]ReportItem, error) {
+	result := make([]ReportItem, 0)
+
+	for page := 0; ; page++ {
+		var response reportItemsResponse
+
+		path := fmt.Sprintf("autoload/v2/reports/%d/items?page=%d&per_page=%d", reportId, page, reportItemsPerPage)
+		if err := c.request(ctx, http.MethodGet, path, nil, &response); err != nil {
+			return nil, err
+		}
+
+		result = append(result, response.Items...)
+
+		if len(response.Items) == 0 || page+1 >= response.Meta.Pages {
+			break
+		}
+	}
+
+	return result, nil
+}
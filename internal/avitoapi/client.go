@@ -0,0 +1,171 @@
+package avitoapi
+
+import (
+	"context"
+	"fmt"
+	"github.com/KirillKhitev/carat_export/internal/config"
+	"github.com/go-resty/resty/v2"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultURL адрес API Avito. В настройках можно задать другой адрес.
+const DefaultURL = "https://api.avito.ru/"
+
+// Client клиент API Avito с авторизацией по client credentials.
+type Client struct {
+	client       *resty.Client
+	m            *sync.Mutex
+	clientId     string
+	clientSecret string
+	token        string
+	expiresAt    time.Time
+}
+
+// NewClient создает клиент с общими ключами доступа из настроек.
+func NewClient() *Client {
+	return NewClientWithCredentials(config.Config.AvitoClientId, config.Config.AvitoClientSecret)
+}
+
+// NewFeedClient создает клиент для аккаунта фида. Если у фида нет своих ключей, используются общие.
+func NewFeedClient(feed config.FeedParams) *Client {
+	if feed.AvitoClientId == "" || feed.AvitoClientSecret == "" {
+		return NewClient()
+	}
+
+	return NewClientWithCredentials(feed.AvitoClientId, feed.AvitoClientSecret)
+}
+
+func NewClientWithCredentials(clientId string, clientSecret string) *Client {
+	return &Client{
+		client:       resty.New(),
+		m:            &sync.Mutex{},
+		clientId:     clientId,
+		clientSecret: clientSecret,
+	}
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in"`
+	TokenType   string `json:"token_type"`
+}
+
+// APIError ошибка, которую возвращает API Avito.
+type APIError struct {
+	Code             int    `json:"code"`
+	Message          string `json:"message"`
+	ErrorCode        string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+func (e *APIError) Error() string {
+	if e.ErrorCode != "" {
+		return fmt.Sprintf("%s: %s", e.ErrorCode, e.ErrorDescription)
+	}
+
+	return fmt.Sprintf("%d: %s", e.Code, e.Message)
+}
+
+// Enabled показывает, заданы ли в настройках общие ключи доступа к API Avito.
+func Enabled() bool {
+	return config.Config.AvitoClientId != "" && config.Config.AvitoClientSecret != ""
+}
+
+// Enabled показывает, заданы ли у клиента ключи доступа.
+func (c *Client) Enabled() bool {
+	return c.clientId != "" && c.clientSecret != ""
+}
+
+// methodURL формирует адрес метода API.
+func methodURL(path string) string {
+	base := config.Config.AvitoApiUrl
+	if base == "" {
+		base = DefaultURL
+	}
+
+	return strings.TrimSuffix(base, "/") + "/" + strings.TrimPrefix(path, "/")
+}
+
+// accessToken возвращает действующий токен доступа, при необходимости получая новый.
+func (c *Client) accessToken(ctx context.Context) (string, error) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	if c.token != "" && time.Now().Before(c.expiresAt) {
+		return c.token, nil
+	}
+
+	contextWithTimeout, cancel := context.WithTimeout(ctx, time.Duration(20*time.Second))
+	defer cancel()
+
+	var result tokenResponse
+	var responseErr APIError
+
+	response, err := c.client.R().
+		SetContext(contextWithTimeout).
+		ForceContentType("application/json").
+		SetFormData(map[string]string{
+			"grant_type":    "client_credentials",
+			"client_id":     c.clientId,
+			"client_secret": c.clientSecret,
+		}).
+		SetResult(&result).
+		SetError(&responseErr).
+		Post(methodURL("token"))
+
+	if err != nil {
+		return "", fmt.Errorf("ошибка получения токена Avito: %w", err)
+	}
+
+	if response.IsError() || result.AccessToken == "" {
+		return "", fmt.Errorf("ошибка получения токена Avito: %s: %w", response.Status(), &responseErr)
+	}
+
+	c.token = result.AccessToken
+	c.expiresAt = time.Now().Add(time.Duration(result.ExpiresIn)*time.Second - time.Minute)
+
+	return c.token, nil
+}
+
+// request выполняет авторизованный запрос к API Avito и разбирает ответ в result.
+func (c *Client) request(ctx context.Context, method, path string, body any, result any) error {
+	token, err := c.accessToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	contextWithTimeout, cancel := context.WithTimeout(ctx, time.Duration(20*time.Second))
+	defer cancel()
+
+	var responseErr APIError
+
+	req := c.client.R().
+		SetContext(contextWithTimeout).
+		ForceContentType("application/json").
+		SetAuthToken(token).
+		SetResult(result).
+		SetError(&responseErr)
+
+	if body != nil {
+		req.SetBody(body)
+	}
+
+	response, err := req.Execute(method, methodURL(path))
+	if err != nil {
+		return err
+	}
+
+	if response.StatusCode() == 401 {
+		c.m.Lock()
+		c.token = ""
+		c.m.Unlock()
+	}
+
+	if response.IsError() {
+		return fmt.Errorf("%s %s: %s: %w", method, path, response.Status(), &responseErr)
+	}
+
+	return nil
+}
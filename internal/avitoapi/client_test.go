@@ -0,0 +1,182 @@
+package avitoapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/KirillKhitev/carat_export/internal/config"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+)
+
+// newStub запускает заглушку API Avito: /token выдает токены "token-1", "token-2"...,
+// остальные запросы с действующим токеном передаются в handler.
+func newStub(t *testing.T, handler http.HandlerFunc) (*Client, *atomic.Int32) {
+	t.Helper()
+
+	var tokens atomic.Int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			if r.FormValue("client_id") != "id" || r.FormValue("client_secret") != "secret" {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"error":"invalid_client","error_description":"wrong credentials"}`)
+				return
+			}
+
+			n := tokens.Add(1)
+			fmt.Fprintf(w, `{"access_token":"token-%d","expires_in":86400,"token_type":"Bearer"}`, n)
+			return
+		}
+
+		if r.Header.Get("Authorization") != fmt.Sprintf("Bearer token-%d", tokens.Load()) {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"code":401,"message":"unauthorized"}`)
+			return
+		}
+
+		handler(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	old := config.Config
+	t.Cleanup(func() { config.Config = old })
+
+	config.Config.AvitoApiUrl = srv.URL
+	config.Config.AvitoClientId = "id"
+	config.Config.AvitoClientSecret = "secret"
+
+	return NewClient(), &tokens
+}
+
+func TestTokenIsReused(t *testing.T) {
+	client, tokens := newStub(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"report_id":42,"status":"success"}`)
+	})
+
+	for i := 0; i < 3; i++ {
+		autoloadReport, err := client.LastCompletedReport(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if autoloadReport.ReportId != 42 {
+			t.Fatalf("report id = %d, want 42", autoloadReport.ReportId)
+		}
+	}
+
+	if n := tokens.Load(); n != 1 {
+		t.Fatalf("token requested %d times, want 1", n)
+	}
+}
+
+func TestTokenIsRenewedAfterUnauthorized(t *testing.T) {
+	var revoked atomic.Bool
+
+	client, tokens := newStub(t, func(w http.ResponseWriter, r *http.Request) {
+		if revoked.CompareAndSwap(true, false) {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"code":401,"message":"token expired"}`)
+			return
+		}
+
+		fmt.Fprint(w, `{"report_id":42}`)
+	})
+
+	if _, err := client.LastCompletedReport(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	revoked.Store(true)
+
+	if _, err := client.LastCompletedReport(context.Background()); err == nil {
+		t.Fatal("expected error for revoked token")
+	}
+
+	if _, err := client.LastCompletedReport(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := tokens.Load(); n != 2 {
+		t.Fatalf("token requested %d times, want 2", n)
+	}
+}
+
+func TestWrongCredentials(t *testing.T) {
+	newStub(t, func(w http.ResponseWriter, r *http.Request) {})
+	config.Config.AvitoClientSecret = "wrong"
+
+	_, err := NewClient().LastCompletedReport(context.Background())
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.ErrorCode != "invalid_client" {
+		t.Fatalf("error = %v, want invalid_client", err)
+	}
+}
+
+func TestReportItemsPagination(t *testing.T) {
+	client, _ := newStub(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/autoload/v2/reports/7/items" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+
+		var response reportItemsResponse
+		response.Items = []ReportItem{{AdId: "ad-" + strconv.Itoa(page)}}
+		response.Meta.Pages = 2
+
+		json.NewEncoder(w).Encode(response)
+	})
+
+	items, err := client.ReportItems(context.Background(), 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(items) != 2 || items[0].AdId != "ad-0" || items[1].AdId != "ad-1" {
+		t.Fatalf("items = %+v", items)
+	}
+}
+
+func TestOrdersPagination(t *testing.T) {
+	client, _ := newStub(t, func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query()["statuses"]; len(got) != 2 || got[0] != "on_confirmation" || got[1] != "ready_to_ship" {
+			t.Errorf("statuses = %v", got)
+		}
+
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+
+		json.NewEncoder(w).Encode(ordersResponse{
+			Orders:  []Order{{Id: "order-" + strconv.Itoa(page)}},
+			HasMore: page < 3,
+		})
+	})
+
+	orders, err := client.Orders(context.Background(), []string{"on_confirmation", "ready_to_ship"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(orders) != 3 || orders[0].Id != "order-1" || orders[2].Id != "order-3" {
+		t.Fatalf("orders = %+v", orders)
+	}
+}
+
+func TestReportItemsError(t *testing.T) {
+	client, _ := newStub(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"code":404,"message":"report not found"}`)
+	})
+
+	_, err := client.ReportItems(context.Background(), 7)
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != 404 || apiErr.Message != "report not found" {
+		t.Fatalf("error = %v, want 404 report not found", err)
+	}
+}
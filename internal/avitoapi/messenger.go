@@ -0,0 +1,79 @@
+package avitoapi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+const chatsPerPage = 100
+
+// Account учетная запись Avito, от имени которой работает клиент.
+type Account struct {
+	Id    int64  `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+	Phone string `json:"phone"`
+}
+
+// Chat чат мессенджера Avito.
+type Chat struct {
+	Id      string      `json:"id"`
+	Created int64       `json:"created"`
+	Updated int64       `json:"updated"`
+	Context ChatContext `json:"context"`
+	Users   []ChatUser  `json:"users"`
+}
+
+// ChatContext объявление, по которому начат чат.
+type ChatContext struct {
+	Type  string `json:"type"`
+	Value struct {
+		Id          int64  `json:"id"`
+		Title       string `json:"title"`
+		Url         string `json:"url"`
+		PriceString string `json:"price_string"`
+	} `json:"value"`
+}
+
+type ChatUser struct {
+	Id   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+type chatsResponse struct {
+	Chats []Chat `json:"chats"`
+}
+
+// Self получает учетную запись Avito, к которой относятся ключи доступа.
+func (c *Client) Self(ctx context.Context) (Account, error) {
+	var result Account
+
+	err := c.request(ctx, http.MethodGet, "core/v1/accounts/self", nil, &result)
+
+	return result, err
+}
+
+// Chats получает чаты по объявлениям пользователя. Чаты приходят от недавно обновленных
+// к давним, поэтому загрузка прекращается на странице, для которой stop вернул true.
+// При stop == nil загружаются все чаты.
+func (c *Client) Chats(ctx context.Context, userId int64, stop func(page []Chat) bool) ([]Chat, error) {
+	result := make([]Chat, 0)
+
+	for offset := 0; ; offset += chatsPerPage {
+		var response chatsResponse
+
+		path := fmt.Sprintf("messenger/v2/accounts/%d/chats?chat_types=u2i&limit=%d&offset=%d", userId, chatsPerPage, offset)
+		if err := c.request(ctx, http.MethodGet, path, nil, &response); err != nil {
+			return nil, err
+		}
+
+		result = append(result, response.Chats...)
+
+		if len(response.Chats) < chatsPerPage || (stop != nil && stop(response.Chats)) {
+			break
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,40 @@
+package avitoapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"testing"
+)
+
+func TestChatsStopsAtKnownPage(t *testing.T) {
+	var requests atomic.Int32
+
+	client, _ := newStub(t, func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+
+		chats := make([]Chat, chatsPerPage)
+		for i := range chats {
+			chats[i] = Chat{Id: "chat-" + strconv.Itoa(offset+i)}
+		}
+
+		json.NewEncoder(w).Encode(chatsResponse{Chats: chats})
+	})
+
+	known := func(page []Chat) bool {
+		return page[0].Id == "chat-"+strconv.Itoa(chatsPerPage)
+	}
+
+	chats, err := client.Chats(context.Background(), 1, known)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(chats) != 2*chatsPerPage || requests.Load() != 2 {
+		t.Fatalf("got %d chats in %d requests, want %d in 2", len(chats), requests.Load(), 2*chatsPerPage)
+	}
+}
@@ -0,0 +1,61 @@
+package avitoapi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// Order заказ Авито Доставки.
+type Order struct {
+	Id            string      `json:"id"`
+	MarketplaceId string      `json:"marketplaceId"`
+	Status        string      `json:"status"`
+	CreatedAt     string      `json:"createdAt"`
+	Items         []OrderItem `json:"items"`
+}
+
+// OrderItem товар в заказе. AvitoId - номер объявления на Avito.
+type OrderItem struct {
+	Id      string `json:"id"`
+	AvitoId string `json:"avitoId"`
+	Title   string `json:"title"`
+	Count   int    `json:"count"`
+	Prices  struct {
+		Price float64 `json:"price"`
+		Total float64 `json:"total"`
+	} `json:"prices"`
+}
+
+type ordersResponse struct {
+	Orders  []Order `json:"orders"`
+	HasMore bool    `json:"hasMore"`
+}
+
+// Orders получает заказы в указанных статусах. Пустой список статусов - все заказы.
+func (c *Client) Orders(ctx context.Context, statuses []string) ([]Order, error) {
+	result := make([]Order, 0)
+
+	for page := 1; ; page++ {
+		query := url.Values{}
+		query.Set("page", fmt.Sprintf("%d", page))
+
+		for _, status := range statuses {
+			query.Add("statuses", status)
+		}
+
+		var response ordersResponse
+		if err := c.request(ctx, http.MethodGet, "order-management/1/orders?"+query.Encode(), nil, &response); err != nil {
+			return nil, err
+		}
+
+		result = append(result, response.Orders...)
+
+		if !response.HasMore || len(response.Orders) == 0 {
+			break
+		}
+	}
+
+	return result, nil
+}
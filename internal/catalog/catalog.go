@@ -0,0 +1,99 @@
+package catalog
+
+import (
+	"encoding/csv"
+	"github.com/KirillKhitev/carat_export/internal/config"
+	"github.com/KirillKhitev/carat_export/internal/fileutil"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	CSVFileName  = "catalog.csv"
+	XLSXFileName = "catalog.xlsx"
+)
+
+func init() {
+	config.ReserveRoute("/"+CSVFileName, "каталог для менеджеров в CSV")
+	config.ReserveRoute("/"+XLSXFileName, "каталог для менеджеров в XLSX")
+}
+
+// Row строка каталога: товар МойСклад в фиде Avito с итоговыми данными объявления.
+type Row struct {
+	Feed     string
+	ID       string
+	Article  string
+	Title    string
+	Price    int
+	Stock    float32
+	Images   []string
+	Category string
+	Exported bool
+	Reason   string
+}
+
+// header заголовок таблицы каталога.
+var header = []string{"Фид", "ID", "Артикул", "Название", "Цена", "Остаток", "Изображения", "Категория", "Выгружен", "Причина"}
+
+// values возвращает значения ячеек строки в порядке заголовка.
+func (r Row) values() []string {
+	exported := "нет"
+	if r.Exported {
+		exported = "да"
+	}
+
+	return []string{
+		r.Feed,
+		r.ID,
+		r.Article,
+		r.Title,
+		strconv.Itoa(r.Price),
+		strconv.FormatFloat(float64(r.Stock), 'f', -1, 32),
+		strings.Join(r.Images, " "),
+		r.Category,
+		exported,
+		r.Reason,
+	}
+}
+
+// CSVPath путь до CSV файла каталога.
+func CSVPath() string {
+	return filepath.Join(config.Config.ReportDir, CSVFileName)
+}
+
+// XLSXPath путь до XLSX файла каталога.
+func XLSXPath() string {
+	return filepath.Join(config.Config.ReportDir, XLSXFileName)
+}
+
+// Save сохраняет каталог в папку отчетов в форматах CSV и XLSX.
+func Save(rows []Row) error {
+	if err := SaveCSV(CSVPath(), rows); err != nil {
+		return err
+	}
+
+	return SaveXLSX(XLSXPath(), rows)
+}
+
+// SaveCSV сохраняет каталог в CSV файл.
+func SaveCSV(path string, rows []Row) error {
+	return fileutil.WriteAtomic(path, func(f io.Writer) error {
+		w := csv.NewWriter(f)
+
+		if err := w.Write(header); err != nil {
+			return err
+		}
+
+		for _, row := range rows {
+			if err := w.Write(row.values()); err != nil {
+				return err
+			}
+		}
+
+		w.Flush()
+
+		return w.Error()
+	})
+}
@@ -0,0 +1,108 @@
+package catalog
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"github.com/KirillKhitev/carat_export/internal/fileutil"
+	"io"
+	"strings"
+)
+
+// Служебные части книги XLSX с одним листом.
+var xlsxParts = []struct {
+	name    string
+	content string
+}{
+	{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/></Types>`},
+	{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`},
+	{"xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Каталог" sheetId="1" r:id="rId1"/></sheets></workbook>`},
+	{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`},
+}
+
+// numericColumns колонки с числами: цена и остаток.
+var numericColumns = map[int]bool{4: true, 5: true}
+
+// SaveXLSX сохраняет каталог в XLSX файл. Книга собирается вручную из XML частей,
+// строки записываются как inline строки без общей таблицы строк.
+func SaveXLSX(path string, rows []Row) error {
+	return fileutil.WriteAtomic(path, func(w io.Writer) error {
+		zw := zip.NewWriter(w)
+
+		for _, part := range xlsxParts {
+			f, err := zw.Create(part.name)
+			if err != nil {
+				return err
+			}
+
+			if _, err := io.WriteString(f, part.content); err != nil {
+				return err
+			}
+		}
+
+		f, err := zw.Create("xl/worksheets/sheet1.xml")
+		if err != nil {
+			return err
+		}
+
+		if err := writeSheet(f, rows); err != nil {
+			return err
+		}
+
+		return zw.Close()
+	})
+}
+
+// writeSheet пишет лист с заголовком и строками каталога.
+func writeSheet(w io.Writer, rows []Row) error {
+	var sb strings.Builder
+
+	sb.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+	sb.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+
+	writeRow(&sb, 1, header, false)
+
+	for i, row := range rows {
+		writeRow(&sb, i+2, row.values(), true)
+	}
+
+	sb.WriteString(`</sheetData></worksheet>`)
+
+	_, err := io.WriteString(w, sb.String())
+
+	return err
+}
+
+func writeRow(sb *strings.Builder, n int, values []string, numbers bool) {
+	fmt.Fprintf(sb, `<row r="%d">`, n)
+
+	for i, value := range values {
+		ref := fmt.Sprintf("%s%d", columnName(i), n)
+
+		if numbers && numericColumns[i] {
+			fmt.Fprintf(sb, `<c r="%s"><v>%s</v></c>`, ref, value)
+			continue
+		}
+
+		fmt.Fprintf(sb, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">`, ref)
+		xml.EscapeText(sb, []byte(value))
+		sb.WriteString(`</t></is></c>`)
+	}
+
+	sb.WriteString(`</row>`)
+}
+
+// columnName возвращает буквенное имя колонки по индексу: 0 - A, 25 - Z, 26 - AA.
+func columnName(i int) string {
+	name := ""
+
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+
+	return name
+}
@@ -5,9 +5,320 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
 	"strconv"
+	"strings"
 )
 
+// FolderParams параметры объявлений для товаров из группы МойСклад.
+type FolderParams struct {
+	Address   string `json:"address"`
+	AdType    string `json:"ad_type"`
+	Condition string `json:"condition"`
+	Category  string `json:"category"`
+	GoodsType string `json:"goods_type"`
+
+	ContactPhone  string `json:"contact_phone"`
+	ManagerName   string `json:"manager_name"`
+	ContactMethod string `json:"contact_method"`
+	ListingFee    string `json:"listing_fee"`
+	AdStatus      string `json:"ad_status"`
+
+	// DescriptionTemplate путь до шаблона описания товаров группы.
+	DescriptionTemplate string `json:"description_template"`
+}
+
+// CategoryField дополнительный элемент объявления Avito, заполняемый из доп. поля МойСклад.
+type CategoryField struct {
+	Name      string `json:"name"`
+	Attribute string `json:"attribute"`
+	Default   string `json:"default"`
+	Required  bool   `json:"required"`
+}
+
+// CategorySchema дополнительные элементы объявлений категории Avito.
+type CategorySchema struct {
+	Category  string          `json:"category"`
+	GoodsType string          `json:"goods_type"`
+	Fields    []CategoryField `json:"fields"`
+}
+
+// WriteBackParams названия доп. полей товаров МойСклад, в которые записываются данные из Avito.
+// Пустое название отключает запись соответствующего поля.
+type WriteBackParams struct {
+	AvitoId    string `json:"avito_id"`
+	AvitoUrl   string `json:"avito_url"`
+	ExportedAt string `json:"exported_at"`
+	Status     string `json:"status"`
+	BatchSize  int    `json:"batch_size"`
+}
+
+// Enabled показывает, настроена ли запись хотя бы одного поля.
+func (w WriteBackParams) Enabled() bool {
+	return w.AvitoId != "" || w.AvitoUrl != "" || w.ExportedAt != "" || w.Status != ""
+}
+
+// AvitoIdAttribute название доп. поля товара с ID объявления Avito. Если запись ID не
+// настроена, ID читается из доп. поля "AvitoId".
+func (w WriteBackParams) AvitoIdAttribute() string {
+	if w.AvitoId != "" {
+		return w.AvitoId
+	}
+
+	return "AvitoId"
+}
+
+// OrdersParams настройки загрузки заказов Авито Доставки в МойСклад.
+// Organization, Counterparty и Store - идентификаторы сущностей МойСклад. Store обязателен,
+// так как товары заказов резервируются на складе.
+type OrdersParams struct {
+	Interval     int      `json:"interval"`
+	Organization string   `json:"organization"`
+	Counterparty string   `json:"counterparty"`
+	Store        string   `json:"store"`
+	Statuses     []string `json:"statuses"`
+}
+
+// Enabled показывает, включена ли загрузка заказов.
+func (o OrdersParams) Enabled() bool {
+	return o.Interval > 0 && o.Organization != "" && o.Counterparty != "" && o.Store != ""
+}
+
+// LeadsParams настройки обработки чатов мессенджера Avito.
+// Employee - идентификатор сотрудника МойСклад, на которого ставятся задачи.
+type LeadsParams struct {
+	Interval int    `json:"interval"`
+	Employee string `json:"employee"`
+	DueHours int    `json:"due_hours"`
+}
+
+// Enabled показывает, включена ли обработка чатов.
+func (l LeadsParams) Enabled() bool {
+	return l.Interval > 0 && l.Employee != ""
+}
+
+// PromotionRule правило платного размещения и продвижения объявлений. Применяется первое
+// подходящее правило, незаполненные условия не проверяются. NewDays - товар впервые выгружен
+// не более указанного числа дней назад.
+type PromotionRule struct {
+	Folder     string `json:"folder"`
+	MinPrice   int    `json:"min_price"`
+	MaxPrice   int    `json:"max_price"`
+	NewDays    int    `json:"new_days"`
+	AdStatus   string `json:"ad_status"`
+	ListingFee string `json:"listing_fee"`
+}
+
+// ScheduleRule сезонная публикация товаров группы: с From по To каждого года, даты вида "MM-DD".
+type ScheduleRule struct {
+	Folder string `json:"folder"`
+	From   string `json:"from"`
+	To     string `json:"to"`
+}
+
+// ImagesParams правила выбора и порядка изображений в объявлениях.
+type ImagesParams struct {
+	// Order порядок изображений: "moysklad" - как в МойСклад (по умолчанию), "filename" - по имени файла.
+	Order string `json:"order"`
+	// Patterns регулярные выражения имен файлов: изображения, подходящие под более ранний шаблон, идут первыми.
+	Patterns []string `json:"patterns"`
+	// MainAttribute доп. поле товара с именем файла главного изображения.
+	MainAttribute string `json:"main_attribute"`
+	// Placeholder URL изображения для товаров без фотографий. Без него такие товары не выгружаются.
+	Placeholder string `json:"placeholder"`
+	// Cover URL брендированной обложки для товаров без фотографий. Используется вместо Placeholder.
+	Cover string `json:"cover"`
+	Max   int    `json:"max"`
+}
+
+// YandexMarketParams настройки YML фида Яндекс Маркета. Params - названия доп. полей
+// МойСклад, которые выгружаются характеристиками товара.
+type YandexMarketParams struct {
+	FilePath  string   `json:"file_path"`
+	URL       string   `json:"url"`
+	ShopName  string   `json:"shop_name"`
+	Company   string   `json:"company"`
+	ShopURL   string   `json:"shop_url"`
+	PriceType string   `json:"price_type"`
+	Params    []string `json:"params"`
+}
+
+// Enabled показывает, включена ли выгрузка для Яндекс Маркета.
+func (y YandexMarketParams) Enabled() bool {
+	return y.FilePath != ""
+}
+
+// OzonCategory категория Ozon для товаров группы МойСклад. Attributes - идентификатор
+// характеристики Ozon -> название доп. поля МойСклад.
+type OzonCategory struct {
+	DescriptionCategoryId int64             `json:"description_category_id"`
+	TypeId                int64             `json:"type_id"`
+	Attributes            map[string]string `json:"attributes"`
+}
+
+// OzonDimensions габариты и вес упаковки товара по умолчанию, в миллиметрах и граммах.
+type OzonDimensions struct {
+	Height int `json:"height"`
+	Width  int `json:"width"`
+	Depth  int `json:"depth"`
+	Weight int `json:"weight"`
+}
+
+// OzonParams настройки синхронизации с Ozon Seller API. Warehouses - название склада
+// МойСклад -> идентификатор склада Ozon, Categories - группа МойСклад -> категория Ozon.
+type OzonParams struct {
+	ApiUrl     string                  `json:"api_url"`
+	ClientId   string                  `json:"client_id"`
+	ApiKey     string                  `json:"api_key"`
+	PriceType  string                  `json:"price_type"`
+	Vat        string                  `json:"vat"`
+	Warehouses map[string]int64        `json:"warehouses"`
+	Categories map[string]OzonCategory `json:"categories"`
+	Dimensions OzonDimensions          `json:"dimensions"`
+}
+
+// Enabled показывает, заданы ли ключи доступа к Ozon Seller API.
+func (o OzonParams) Enabled() bool {
+	return o.ClientId != "" && o.ApiKey != ""
+}
+
+// Category возвращает категорию Ozon для группы МойСклад, ищется самая длинная подходящая группа.
+func (o OzonParams) Category(folder string) (OzonCategory, bool) {
+	result := OzonCategory{}
+	matched := -1
+
+	for name, category := range o.Categories {
+		if folder != name && !strings.HasPrefix(folder, name+"/") {
+			continue
+		}
+
+		if len(name) > matched {
+			result = category
+			matched = len(name)
+		}
+	}
+
+	return result, matched >= 0
+}
+
+// WildberriesParams настройки синхронизации цен и остатков с Wildberries. Карточки товаров
+// ведутся вручную, товары МойСклад сопоставляются с ними по доп. полям с nmID и баркодом.
+// Warehouses - название склада МойСклад -> идентификатор склада продавца WB.
+type WildberriesParams struct {
+	ApiToken          string           `json:"api_token"`
+	MarketplaceApiUrl string           `json:"marketplace_api_url"`
+	PricesApiUrl      string           `json:"prices_api_url"`
+	NmIdAttribute     string           `json:"nm_id_attribute"`
+	BarcodeAttribute  string           `json:"barcode_attribute"`
+	PriceType         string           `json:"price_type"`
+	Discount          int              `json:"discount"`
+	Warehouses        map[string]int64 `json:"warehouses"`
+}
+
+// Enabled показывает, задан ли токен доступа к API Wildberries.
+func (w WildberriesParams) Enabled() bool {
+	return w.ApiToken != ""
+}
+
+// GoogleMerchantParams настройки RSS фида Google Merchant Center. ProductLink - адрес страницы
+// товара, в котором {id} и {article} заменяются на ID и артикул товара МойСклад.
+type GoogleMerchantParams struct {
+	FilePath       string `json:"file_path"`
+	URL            string `json:"url"`
+	Title          string `json:"title"`
+	Link           string `json:"link"`
+	Description    string `json:"description"`
+	ProductLink    string `json:"product_link"`
+	PriceType      string `json:"price_type"`
+	BrandAttribute string `json:"brand_attribute"`
+	Brand          string `json:"brand"`
+	Condition      string `json:"condition"`
+}
+
+// Enabled показывает, включена ли выгрузка для Google Merchant Center.
+func (g GoogleMerchantParams) Enabled() bool {
+	return g.FilePath != ""
+}
+
+// SiteParams настройки статического каталога товаров. BaseURL - внешний адрес сервера для
+// sitemap.xml и OpenGraph, URL - путь каталога на HTTP сервере, Feed - фид Avito, товары
+// которого попадают в каталог.
+type SiteParams struct {
+	Dir     string `json:"dir"`
+	URL     string `json:"url"`
+	BaseURL string `json:"base_url"`
+	Title   string `json:"title"`
+	Feed    string `json:"feed"`
+}
+
+// Enabled показывает, включена ли генерация каталога.
+func (s SiteParams) Enabled() bool {
+	return s.Dir != ""
+}
+
+// VKParams настройки синхронизации товаров сообщества ВКонтакте. CategoryId - категория
+// товаров VK, в которую попадают все товары.
+type VKParams struct {
+	ApiUrl      string `json:"api_url"`
+	AccessToken string `json:"access_token"`
+	GroupId     int64  `json:"group_id"`
+	CategoryId  int    `json:"category_id"`
+	PriceType   string `json:"price_type"`
+}
+
+// Enabled показывает, настроена ли синхронизация с VK.
+func (v VKParams) Enabled() bool {
+	return v.AccessToken != "" && v.GroupId > 0
+}
+
+// TelegramParams настройки публикации новых товаров в канале Telegram. ChatId - ID или
+// @username канала, Template - путь до шаблона подписи к посту, MaxPosts - сколько товаров
+// публиковать за один запуск.
+type TelegramParams struct {
+	ApiUrl   string `json:"api_url"`
+	BotToken string `json:"bot_token"`
+	ChatId   string `json:"chat_id"`
+	Template string `json:"template"`
+	MaxPosts int    `json:"max_posts"`
+}
+
+// Enabled показывает, настроена ли публикация в Telegram.
+func (t TelegramParams) Enabled() bool {
+	return t.BotToken != "" && t.ChatId != ""
+}
+
+// DefaultFeedName имя фида, который формируется из общих настроек, если фиды не заданы.
+const DefaultFeedName = "avito"
+
+// FeedParams настройки одного фида Avito (например, для отдельного аккаунта).
+// Все фиды формируются из одного забора товаров МойСклад.
+type FeedParams struct {
+	Name     string `json:"name"`
+	FilePath string `json:"file_path"`
+	// URL путь, по которому фид отдается HTTP сервером.
+	URL string `json:"url"`
+	// PriceType название типа цены МойСклад. Пустое значение - первая цена продажи.
+	PriceType string `json:"price_type"`
+
+	Folders        []string `json:"folders"`
+	ExcludeFolders []string `json:"exclude_folders"`
+	MinPrice       int      `json:"min_price"`
+	MinStock       float32  `json:"min_stock"`
+
+	// Defaults значения полей объявлений фида, если они не заданы у товара или его группы.
+	Defaults FolderParams `json:"defaults"`
+
+	MaxImages        int    `json:"max_images"`
+	PlaceholderImage string `json:"placeholder_image"`
+	CoverImage       string `json:"cover_image"`
+
+	// Ключи API Avito аккаунта фида. Если не заданы, используются общие.
+	AvitoClientId     string `json:"avito_client_id"`
+	AvitoClientSecret string `json:"avito_client_secret"`
+}
+
 type Params struct {
 	MoySkladUrl           string `json:"moy_sklad_url"`
 	MoySkladLogin         string `json:"moy_sklad_login"`
@@ -22,6 +333,43 @@ type Params struct {
 	ImageWorkers          int    `json:"image_workers"`
 	ProductDescriptionAdd string `json:"product_description_add"`
 	NeedDownloadProducts  bool   `json:"need_download_products"`
+	ReportDir             string `json:"report_dir"`
+	HistoryDir            string `json:"history_dir"`
+	AvitoRemovedKeepDays  int    `json:"avito_removed_keep_days"`
+	AvitoAddress          string `json:"avito_address"`
+	AvitoAdType           string `json:"avito_ad_type"`
+	AvitoCondition        string `json:"avito_condition"`
+	DescriptionTemplate   string `json:"description_template"`
+	AvitoCategory         string `json:"avito_category"`
+	AvitoGoodsType        string `json:"avito_goods_type"`
+	AvitoApiUrl           string `json:"avito_api_url"`
+	AvitoClientId         string `json:"avito_client_id"`
+	AvitoClientSecret     string `json:"avito_client_secret"`
+	AvitoContactPhone     string `json:"avito_contact_phone"`
+	AvitoManagerName      string `json:"avito_manager_name"`
+	AvitoContactMethod    string `json:"avito_contact_method"`
+	AvitoListingFee       string `json:"avito_listing_fee"`
+	AvitoAdStatus         string `json:"avito_ad_status"`
+
+	// Exporters форматы выгрузки, которые нужно формировать. Пустой список - все настроенные форматы.
+	Exporters      []string                `json:"exporters"`
+	Folders        map[string]FolderParams `json:"folders"`
+	StoreAddresses map[string]string       `json:"store_addresses"`
+	Schemas        []CategorySchema        `json:"category_schemas"`
+	WriteBack      WriteBackParams         `json:"write_back"`
+	Orders         OrdersParams            `json:"avito_orders"`
+	Leads          LeadsParams             `json:"avito_leads"`
+	PromotionRules []PromotionRule         `json:"promotion_rules"`
+	ScheduleRules  []ScheduleRule          `json:"schedule_rules"`
+	Feeds          []FeedParams            `json:"feeds"`
+	Images         ImagesParams            `json:"images"`
+	YandexMarket   YandexMarketParams      `json:"yandex_market"`
+	Ozon           OzonParams              `json:"ozon"`
+	Wildberries    WildberriesParams       `json:"wildberries"`
+	GoogleMerchant GoogleMerchantParams    `json:"google_merchant"`
+	Site           SiteParams              `json:"site"`
+	VK             VKParams                `json:"vk"`
+	Telegram       TelegramParams          `json:"telegram"`
 }
 
 var Config Params = Params{}
@@ -56,8 +404,76 @@ func (f *Params) Parse() error {
 	flag.StringVar(&f.ProductDescriptionAdd, "da", c.ProductDescriptionAdd, "Дополнительное описание товара")
 	flag.IntVar(&f.ImageWorkers, "iw", c.ImageWorkers, "Количество потоков для скачивания изображений")
 	flag.BoolVar(&f.NeedDownloadProducts, "nd", c.NeedDownloadProducts, "Начинать ли выгрузку при запуске")
+	flag.StringVar(&f.ReportDir, "rd", c.ReportDir, "Путь до папки отчетов о выгрузке")
+	flag.StringVar(&f.HistoryDir, "hd", c.HistoryDir, "Путь до папки с историей выгрузок")
+	flag.IntVar(&f.AvitoRemovedKeepDays, "rk", c.AvitoRemovedKeepDays, "Сколько дней держать в выгрузке закрытые объявления")
+	flag.StringVar(&f.AvitoAddress, "aa", c.AvitoAddress, "Avito адрес по умолчанию")
+	flag.StringVar(&f.AvitoAdType, "at", c.AvitoAdType, "Avito вид объявления по умолчанию")
+	flag.StringVar(&f.AvitoCondition, "ac", c.AvitoCondition, "Avito состояние товара по умолчанию")
+	flag.StringVar(&f.DescriptionTemplate, "dt", c.DescriptionTemplate, "Путь до шаблона описания товара")
+	flag.StringVar(&f.AvitoCategory, "ag", c.AvitoCategory, "Avito категория по умолчанию")
+	flag.StringVar(&f.AvitoGoodsType, "gt", c.AvitoGoodsType, "Avito вид товара по умолчанию")
+	flag.StringVar(&f.AvitoApiUrl, "au", c.AvitoApiUrl, "Avito URL API")
+	flag.StringVar(&f.AvitoClientId, "ai", c.AvitoClientId, "Avito API client_id")
+	flag.StringVar(&f.AvitoClientSecret, "as", c.AvitoClientSecret, "Avito API client_secret")
+	flag.StringVar(&f.AvitoContactPhone, "cp", c.AvitoContactPhone, "Avito телефон для связи по умолчанию")
+	flag.StringVar(&f.AvitoManagerName, "mn", c.AvitoManagerName, "Avito имя менеджера по умолчанию")
+	flag.StringVar(&f.AvitoContactMethod, "cm", c.AvitoContactMethod, "Avito способ связи по умолчанию")
+	flag.StringVar(&f.AvitoListingFee, "lf", c.AvitoListingFee, "Avito вариант платного размещения по умолчанию")
+	flag.StringVar(&f.AvitoAdStatus, "st", c.AvitoAdStatus, "Avito услуга продвижения по умолчанию")
 	flag.Parse()
 
+	f.Folders = c.Folders
+	f.StoreAddresses = c.StoreAddresses
+	f.Schemas = c.Schemas
+	f.WriteBack = c.WriteBack
+	f.Orders = c.Orders
+	f.Leads = c.Leads
+	f.PromotionRules = c.PromotionRules
+	f.ScheduleRules = c.ScheduleRules
+	f.Feeds = c.Feeds
+	f.Images = c.Images
+	f.Exporters = c.Exporters
+	f.YandexMarket = c.YandexMarket
+	f.Ozon = c.Ozon
+	f.Wildberries = c.Wildberries
+	f.GoogleMerchant = c.GoogleMerchant
+	f.Site = c.Site
+	f.VK = c.VK
+	f.Telegram = c.Telegram
+
+	if f.Telegram.MaxPosts <= 0 {
+		f.Telegram.MaxPosts = 10
+	}
+
+	if f.Site.URL == "" {
+		f.Site.URL = "/catalog/"
+	}
+
+	if !strings.HasSuffix(f.Site.URL, "/") {
+		f.Site.URL += "/"
+	}
+
+	if f.GoogleMerchant.URL == "" {
+		f.GoogleMerchant.URL = "/google_merchant.xml"
+	}
+
+	if f.GoogleMerchant.BrandAttribute == "" {
+		f.GoogleMerchant.BrandAttribute = "Бренд"
+	}
+
+	if f.Wildberries.NmIdAttribute == "" {
+		f.Wildberries.NmIdAttribute = "WB nmID"
+	}
+
+	if f.Wildberries.BarcodeAttribute == "" {
+		f.Wildberries.BarcodeAttribute = "WB баркод"
+	}
+
+	if f.YandexMarket.URL == "" {
+		f.YandexMarket.URL = "/yandex_market.xml"
+	}
+
 	if envMoySkladUrl := os.Getenv(`MOYSKLAD_URL`); envMoySkladUrl != `` {
 		f.MoySkladUrl = envMoySkladUrl
 	}
@@ -114,6 +530,78 @@ func (f *Params) Parse() error {
 		f.ProductDescriptionAdd = envProductDescriptionAdd
 	}
 
+	if envReportDir := os.Getenv(`REPORT_DIR`); envReportDir != `` {
+		f.ReportDir = envReportDir
+	}
+
+	if envHistoryDir := os.Getenv(`HISTORY_DIR`); envHistoryDir != `` {
+		f.HistoryDir = envHistoryDir
+	}
+
+	if envAvitoRemovedKeepDays := os.Getenv("AVITO_REMOVED_KEEP_DAYS"); envAvitoRemovedKeepDays != "" {
+		if val, err := strconv.Atoi(envAvitoRemovedKeepDays); err == nil {
+			f.AvitoRemovedKeepDays = val
+		} else {
+			return fmt.Errorf("неверное значение переменной среды AVITO_REMOVED_KEEP_DAYS: %s", envAvitoRemovedKeepDays)
+		}
+	}
+
+	if envAvitoAddress := os.Getenv(`AVITO_ADDRESS`); envAvitoAddress != `` {
+		f.AvitoAddress = envAvitoAddress
+	}
+
+	if envAvitoAdType := os.Getenv(`AVITO_AD_TYPE`); envAvitoAdType != `` {
+		f.AvitoAdType = envAvitoAdType
+	}
+
+	if envAvitoCondition := os.Getenv(`AVITO_CONDITION`); envAvitoCondition != `` {
+		f.AvitoCondition = envAvitoCondition
+	}
+
+	if envDescriptionTemplate := os.Getenv(`DESCRIPTION_TEMPLATE`); envDescriptionTemplate != `` {
+		f.DescriptionTemplate = envDescriptionTemplate
+	}
+
+	if envAvitoCategory := os.Getenv(`AVITO_CATEGORY`); envAvitoCategory != `` {
+		f.AvitoCategory = envAvitoCategory
+	}
+
+	if envAvitoGoodsType := os.Getenv(`AVITO_GOODS_TYPE`); envAvitoGoodsType != `` {
+		f.AvitoGoodsType = envAvitoGoodsType
+	}
+
+	if envAvitoApiUrl := os.Getenv(`AVITO_API_URL`); envAvitoApiUrl != `` {
+		f.AvitoApiUrl = envAvitoApiUrl
+	}
+
+	if envAvitoClientId := os.Getenv(`AVITO_CLIENT_ID`); envAvitoClientId != `` {
+		f.AvitoClientId = envAvitoClientId
+	}
+
+	if envAvitoClientSecret := os.Getenv(`AVITO_CLIENT_SECRET`); envAvitoClientSecret != `` {
+		f.AvitoClientSecret = envAvitoClientSecret
+	}
+
+	if envAvitoContactPhone := os.Getenv(`AVITO_CONTACT_PHONE`); envAvitoContactPhone != `` {
+		f.AvitoContactPhone = envAvitoContactPhone
+	}
+
+	if envAvitoManagerName := os.Getenv(`AVITO_MANAGER_NAME`); envAvitoManagerName != `` {
+		f.AvitoManagerName = envAvitoManagerName
+	}
+
+	if envAvitoContactMethod := os.Getenv(`AVITO_CONTACT_METHOD`); envAvitoContactMethod != `` {
+		f.AvitoContactMethod = envAvitoContactMethod
+	}
+
+	if envAvitoListingFee := os.Getenv(`AVITO_LISTING_FEE`); envAvitoListingFee != `` {
+		f.AvitoListingFee = envAvitoListingFee
+	}
+
+	if envAvitoAdStatus := os.Getenv(`AVITO_AD_STATUS`); envAvitoAdStatus != `` {
+		f.AvitoAdStatus = envAvitoAdStatus
+	}
+
 	if f.MoySkladUrl == "" {
 		return fmt.Errorf("Пустой МойСклад URL API")
 	}
@@ -126,11 +614,253 @@ func (f *Params) Parse() error {
 		return fmt.Errorf("Пустой МойСклад Пароль")
 	}
 
+	if f.Orders.Interval > 0 && f.Orders.Store == "" {
+		return fmt.Errorf("для загрузки заказов Avito должен быть задан склад резерва avito_orders.store")
+	}
+
+	for _, pattern := range f.Images.Patterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("неверный шаблон имени изображения %q: %w", pattern, err)
+		}
+	}
+
+	names := make(map[string]struct{}, len(f.Feeds))
+	for _, feed := range f.Feeds {
+		if feed.Name == "" || feed.FilePath == "" {
+			return fmt.Errorf("у фида должны быть заданы name и file_path")
+		}
+
+		if _, ok := names[feed.Name]; ok {
+			return fmt.Errorf("повторяется имя фида: %s", feed.Name)
+		}
+
+		names[feed.Name] = struct{}{}
+	}
+
+	return f.checkOutputs()
+}
+
+// routes пути HTTP сервера, занятые служебными файлами, и их назначение.
+var routes = make(map[string]string)
+
+// ReserveRoute отмечает путь HTTP сервера как занятый служебным файлом, чтобы выгрузке нельзя
+// было задать такой же url. Путь, оканчивающийся на "/", занимает и все вложенные пути.
+func ReserveRoute(path, name string) {
+	routes[path] = name
+}
+
+// checkOutputs проверяет, что у фидов и других файловых выгрузок не совпадают пути файлов
+// и адреса на HTTP сервере, а адреса не заняты служебными файлами и каталогом: иначе выгрузки
+// перезаписывают файлы друг друга, а сервер не запускается из-за повторной регистрации адреса.
+func (f *Params) checkOutputs() error {
+	type output struct {
+		name     string
+		filePath string
+		url      string
+	}
+
+	outputs := make([]output, 0, len(f.Feeds)+2)
+	for _, feed := range f.AvitoFeeds() {
+		outputs = append(outputs, output{"фид " + feed.Name, feed.FilePath, feed.URL})
+	}
+
+	if f.YandexMarket.Enabled() {
+		outputs = append(outputs, output{"Яндекс Маркет", f.YandexMarket.FilePath, f.YandexMarket.URL})
+	}
+
+	if f.GoogleMerchant.Enabled() {
+		outputs = append(outputs, output{"Google Merchant", f.GoogleMerchant.FilePath, f.GoogleMerchant.URL})
+	}
+
+	reserved := make(map[string]string, len(routes)+1)
+	for path, name := range routes {
+		reserved[path] = name
+	}
+
+	if f.Site.Enabled() {
+		if name, ok := reservedRoute(reserved, f.Site.URL); ok {
+			return fmt.Errorf("url каталога занят: %s (%s)", f.Site.URL, name)
+		}
+
+		reserved[f.Site.URL] = "каталог"
+	}
+
+	filePaths := make(map[string]string, len(outputs))
+	urls := make(map[string]string, len(outputs))
+
+	for _, o := range outputs {
+		if other, ok := filePaths[filepath.Clean(o.filePath)]; ok && o.filePath != "" {
+			return fmt.Errorf("у выгрузок %s и %s совпадает file_path: %s", other, o.name, o.filePath)
+		}
+
+		if other, ok := urls[o.url]; ok {
+			return fmt.Errorf("у выгрузок %s и %s совпадает url: %s", other, o.name, o.url)
+		}
+
+		if name, ok := reservedRoute(reserved, o.url); ok {
+			return fmt.Errorf("url выгрузки %s занят: %s (%s)", o.name, o.url, name)
+		}
+
+		filePaths[filepath.Clean(o.filePath)] = o.name
+		urls[o.url] = o.name
+	}
+
 	return nil
 }
 
+// reservedRoute возвращает назначение занятого пути, совпадающего с url или включающего его.
+// Корень "/" не занимает вложенные пути: более точный адрес на сервере имеет приоритет.
+func reservedRoute(reserved map[string]string, url string) (string, bool) {
+	for path, name := range reserved {
+		if url == path || (path != "/" && strings.HasSuffix(path, "/") && strings.HasPrefix(url, path)) {
+			return name, true
+		}
+	}
+
+	return "", false
+}
+
+// Folder возвращает параметры самой вложенной настроенной группы, в которую входит товар.
+// path - путь группы товара в МойСклад, например "Монеты/Россия".
+func (f *Params) Folder(path string) FolderParams {
+	result := FolderParams{}
+	matched := -1
+
+	for name, params := range f.Folders {
+		if path != name && !strings.HasPrefix(path, name+"/") {
+			continue
+		}
+
+		if len(name) > matched {
+			result = params
+			matched = len(name)
+		}
+	}
+
+	return result
+}
+
+// AvitoFeeds возвращает фиды Avito. Если фиды не заданы, возвращает один фид из общих настроек,
+// который отдается по /products.xml.
+func (f *Params) AvitoFeeds() []FeedParams {
+	if len(f.Feeds) == 0 {
+		return []FeedParams{{
+			Name:     DefaultFeedName,
+			FilePath: f.AvitoFilePath,
+			URL:      "/products.xml",
+		}}
+	}
+
+	feeds := make([]FeedParams, 0, len(f.Feeds))
+
+	for _, feed := range f.Feeds {
+		if feed.URL == "" {
+			feed.URL = "/feeds/" + feed.Name + ".xml"
+		}
+
+		feeds = append(feeds, feed)
+	}
+
+	return feeds
+}
+
+// ImagesParams возвращает правила изображений фида: общие правила с ограничениями фида.
+func (feed FeedParams) ImagesParams(images ImagesParams) ImagesParams {
+	if feed.MaxImages > 0 {
+		images.Max = feed.MaxImages
+	}
+
+	if feed.PlaceholderImage != "" {
+		images.Placeholder = feed.PlaceholderImage
+	}
+
+	if feed.CoverImage != "" {
+		images.Cover = feed.CoverImage
+	}
+
+	return images
+}
+
+// ExportEnabled показывает, включен ли формат выгрузки в настройках.
+func (f *Params) ExportEnabled(format string) bool {
+	return len(f.Exporters) == 0 || slices.Contains(f.Exporters, format)
+}
+
+// HasPlaceholderImage показывает, задана ли заглушка или обложка для товаров без фотографий
+// хотя бы в одном фиде.
+func (f *Params) HasPlaceholderImage() bool {
+	for _, feed := range f.AvitoFeeds() {
+		if images := feed.ImagesParams(f.Images); images.Placeholder != "" || images.Cover != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Match проверяет, подходит ли группа товара под условия фида.
+func (feed FeedParams) Match(folder string) bool {
+	inFolder := func(name string) bool {
+		return folder == name || strings.HasPrefix(folder, name+"/")
+	}
+
+	for _, name := range feed.ExcludeFolders {
+		if inFolder(name) {
+			return false
+		}
+	}
+
+	if len(feed.Folders) == 0 {
+		return true
+	}
+
+	for _, name := range feed.Folders {
+		if inFolder(name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// String возвращает настройки в JSON со скрытыми паролями, ключами и токенами.
 func (f *Params) String() string {
 	r, _ := json.Marshal(f)
 
 	return string(r)
 }
+
+// MarshalJSON кодирует настройки со скрытыми паролями, ключами и токенами, чтобы секреты
+// не попадали в лог и при выводе структуры целиком.
+func (f Params) MarshalJSON() ([]byte, error) {
+	// params не наследует MarshalJSON, иначе json.Marshal уйдет в рекурсию.
+	type params Params
+
+	c := params(f)
+
+	c.MoySkladPassword = maskSecret(c.MoySkladPassword)
+	c.AvitoClientSecret = maskSecret(c.AvitoClientSecret)
+	c.Ozon.ApiKey = maskSecret(c.Ozon.ApiKey)
+	c.Wildberries.ApiToken = maskSecret(c.Wildberries.ApiToken)
+	c.VK.AccessToken = maskSecret(c.VK.AccessToken)
+	c.Telegram.BotToken = maskSecret(c.Telegram.BotToken)
+
+	if f.Feeds != nil {
+		c.Feeds = make([]FeedParams, len(f.Feeds))
+		for i, feed := range f.Feeds {
+			feed.AvitoClientSecret = maskSecret(feed.AvitoClientSecret)
+			c.Feeds[i] = feed
+		}
+	}
+
+	return json.Marshal(c)
+}
+
+// maskSecret скрывает значение секрета для вывода в лог.
+func maskSecret(secret string) string {
+	if secret == "" {
+		return ""
+	}
+
+	return "***"
+}
@@ -0,0 +1,145 @@
+package config
+
+import (
+	"bytes"
+	"github.com/sirupsen/logrus"
+	"strings"
+	"testing"
+)
+
+func TestLoggedConfigHasNoSecrets(t *testing.T) {
+	params := Params{
+		MoySkladLogin:     "admin@shop",
+		MoySkladPassword:  "ms-password",
+		AvitoClientId:     "avito-id",
+		AvitoClientSecret: "avito-secret",
+		Ozon:              OzonParams{ClientId: "ozon-client", ApiKey: "ozon-key"},
+		Wildberries:       WildberriesParams{ApiToken: "wb-token"},
+		VK:                VKParams{AccessToken: "vk-token", GroupId: 1},
+		Telegram:          TelegramParams{BotToken: "tg-token", ChatId: "@shop"},
+		Feeds:             []FeedParams{{Name: "second", AvitoClientId: "feed-id", AvitoClientSecret: "feed-secret"}},
+	}
+
+	var out bytes.Buffer
+
+	log := logrus.New()
+	log.SetOutput(&out)
+	log.SetFormatter(&logrus.JSONFormatter{})
+
+	log.WithFields(logrus.Fields{
+		"config": params,
+	}).Logln(logrus.InfoLevel, "Запустили приложение")
+
+	log.WithFields(logrus.Fields{
+		"config": &params,
+	}).Logln(logrus.InfoLevel, "Запустили приложение")
+
+	logged := out.String() + params.String()
+
+	for _, secret := range []string{"ms-password", "avito-secret", "ozon-key", "wb-token", "vk-token", "tg-token", "feed-secret"} {
+		if strings.Contains(logged, secret) {
+			t.Errorf("secret %q is logged: %s", secret, logged)
+		}
+	}
+
+	for _, visible := range []string{"admin@shop", "avito-id", "ozon-client", "feed-id"} {
+		if !strings.Contains(logged, visible) {
+			t.Errorf("setting %q is missing in log: %s", visible, logged)
+		}
+	}
+
+	if params.MoySkladPassword != "ms-password" || params.Feeds[0].AvitoClientSecret != "feed-secret" {
+		t.Fatal("logging changed the settings")
+	}
+}
+
+func TestCheckOutputs(t *testing.T) {
+	oldRoutes := routes
+	t.Cleanup(func() { routes = oldRoutes })
+
+	routes = map[string]string{"/images/": "изображения товаров", "/api/": "API каталога", "/report.json": "отчет о выгрузке в JSON"}
+
+	feed := func(name, filePath, url string) FeedParams {
+		return FeedParams{Name: name, FilePath: filePath, URL: url}
+	}
+
+	tests := []struct {
+		name    string
+		params  Params
+		wantErr bool
+	}{
+		{
+			name:   "default feed",
+			params: Params{AvitoFilePath: "products.xml"},
+		},
+		{
+			name:   "different feeds",
+			params: Params{Feeds: []FeedParams{feed("a", "a.xml", ""), feed("b", "b.xml", "")}},
+		},
+		{
+			name:    "same file path",
+			params:  Params{Feeds: []FeedParams{feed("a", "./feeds/a.xml", ""), feed("b", "feeds/a.xml", "")}},
+			wantErr: true,
+		},
+		{
+			name:    "same url",
+			params:  Params{Feeds: []FeedParams{feed("a", "a.xml", "/avito.xml"), feed("b", "b.xml", "/avito.xml")}},
+			wantErr: true,
+		},
+		{
+			name:    "url of fixed file",
+			params:  Params{Feeds: []FeedParams{feed("a", "a.xml", "/report.json")}},
+			wantErr: true,
+		},
+		{
+			name:    "url under fixed prefix",
+			params:  Params{Feeds: []FeedParams{feed("a", "a.xml", "/images/a.xml")}},
+			wantErr: true,
+		},
+		{
+			name:    "url under site",
+			params:  Params{Feeds: []FeedParams{feed("a", "a.xml", "/catalog/a.xml")}, Site: SiteParams{Dir: "site", URL: "/catalog/"}},
+			wantErr: true,
+		},
+		{
+			name:   "url under disabled site",
+			params: Params{Feeds: []FeedParams{feed("a", "a.xml", "/catalog/a.xml")}, Site: SiteParams{URL: "/catalog/"}},
+		},
+		{
+			name:    "yandex market on feed url",
+			params:  Params{AvitoFilePath: "products.xml", YandexMarket: YandexMarketParams{FilePath: "yml.xml", URL: "/products.xml"}},
+			wantErr: true,
+		},
+		{
+			name:    "yandex market on feed file",
+			params:  Params{AvitoFilePath: "products.xml", YandexMarket: YandexMarketParams{FilePath: "products.xml", URL: "/yml.xml"}},
+			wantErr: true,
+		},
+		{
+			name: "google merchant on yandex market url",
+			params: Params{
+				AvitoFilePath:  "products.xml",
+				YandexMarket:   YandexMarketParams{FilePath: "yml.xml", URL: "/market.xml"},
+				GoogleMerchant: GoogleMerchantParams{FilePath: "google.xml", URL: "/market.xml"},
+			},
+			wantErr: true,
+		},
+		{
+			name:    "site on api prefix",
+			params:  Params{Site: SiteParams{Dir: "site", URL: "/api/"}},
+			wantErr: true,
+		},
+		{
+			name:   "site on root does not take feed urls",
+			params: Params{Site: SiteParams{Dir: "site", URL: "/"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.params.checkOutputs(); (err != nil) != tt.wantErr {
+				t.Fatalf("checkOutputs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -2,12 +2,15 @@ package controller
 
 import (
 	"context"
-	"github.com/KirillKhitev/carat_export/internal/avito"
+	"github.com/KirillKhitev/carat_export/internal/catalog"
 	"github.com/KirillKhitev/carat_export/internal/config"
+	"github.com/KirillKhitev/carat_export/internal/export"
+	"github.com/KirillKhitev/carat_export/internal/history"
 	"github.com/KirillKhitev/carat_export/internal/logger"
+	"github.com/KirillKhitev/carat_export/internal/report"
 	"github.com/KirillKhitev/carat_export/internal/storage"
 	"github.com/sirupsen/logrus"
-	"strings"
+	"sort"
 	"sync"
 	"time"
 )
@@ -17,6 +20,7 @@ type Controller struct {
 	wgImageWorkers       *sync.WaitGroup
 	stopImageWorkersChan chan struct{}
 	productIdsChan       chan string
+	report               *report.Report
 }
 
 func NewController() *Controller {
@@ -25,6 +29,7 @@ func NewController() *Controller {
 		wgImageWorkers:       &sync.WaitGroup{},
 		stopImageWorkersChan: make(chan struct{}),
 		productIdsChan:       make(chan string),
+		report:               report.New(),
 	}
 }
 
@@ -46,6 +51,7 @@ func (c *Controller) Close() error {
 func (c *Controller) Clear() {
 	c.storage.Clear()
 	c.stopImageWorkersChan = make(chan struct{})
+	c.report = report.New()
 }
 
 func (c *Controller) startProductsProcess(ctx context.Context) {
@@ -70,6 +76,8 @@ func (c *Controller) downloadProducts(ctx context.Context, needDo bool) {
 
 	logger.Log.Logln(logrus.InfoLevel, "Начинаем выгрузку")
 
+	run := history.Run{StartedAt: time.Now()}
+
 	c.startImageWorkers(ctx)
 
 	if err := c.storage.GetProductsList(ctx); err != nil {
@@ -80,6 +88,12 @@ func (c *Controller) downloadProducts(ctx context.Context, needDo bool) {
 		return
 	}
 
+	if err := c.storage.GetStockByStore(ctx); err != nil {
+		logger.Log.WithFields(logrus.Fields{
+			"error": err,
+		}).Logln(logrus.ErrorLevel, "Ошибка при получении остатков товаров по складам")
+	}
+
 	for id, _ := range c.storage.Products {
 		time.Sleep(time.Millisecond * 300)
 		c.productIdsChan <- id
@@ -89,14 +103,67 @@ func (c *Controller) downloadProducts(ctx context.Context, needDo bool) {
 
 	c.wgImageWorkers.Wait()
 
-	products := c.convertProductsToAvito(c.storage.Products)
+	c.reportExcluded(c.storage.Excluded)
+
+	exportRun := export.NewRun(c.storage.Products, c.report, &run)
+	exportRun.AllProducts = c.storage.AllProducts()
+	exportRun.StoresLoaded = c.storage.StoresLoaded
+
+	avitoFeeds := avitoFeedNames()
+	exported := true
+
+	for _, exporter := range export.Enabled() {
+		if err := export.Export(ctx, exporter, exportRun); err != nil {
+			// Снимок каталога строится по фидам Avito, ошибки синхронизации с маркетплейсами
+			// на него не влияют.
+			if _, ok := avitoFeeds[exporter.Name()]; ok {
+				exported = false
+			}
+
+			logger.Log.WithFields(logrus.Fields{
+				"error":    err,
+				"exporter": exporter.Name(),
+			}).Log(logrus.ErrorLevel, "Ошибка выгрузки товаров")
+		}
+	}
+
+	c.writeBackToMoySklad(ctx, run)
+
+	if err := c.report.Save(); err != nil {
+		logger.Log.WithFields(logrus.Fields{
+			"error": err,
+		}).Log(logrus.ErrorLevel, "Ошибка при сохранении отчета о выгрузке")
+	}
 
-	if err := avito.CreateAutoloadFile(products); err != nil {
+	if err := catalog.Save(c.catalogRows(exportRun.Listings)); err != nil {
 		logger.Log.WithFields(logrus.Fields{
 			"error": err,
-		}).Log(logrus.ErrorLevel, "Ошибка при сохранении товаров в файл выгрузки Avito")
+		}).Log(logrus.ErrorLevel, "Ошибка при сохранении каталога выгрузки")
+	}
+
+	c.generateSite(exportRun.Listings)
+
+	// Снимок каталога обновляется только после успешной выгрузки, иначе API и публикации
+	// продолжают работать с последним успешным снимком.
+	if exported {
+		catalogSnapshot := c.catalogSnapshot(exportRun.Listings)
+		c.saveSnapshot(catalogSnapshot)
+		c.publishTelegram(ctx, catalogSnapshot)
+	} else {
+		logger.Log.Log(logrus.WarnLevel, "Выгрузка фидов Avito завершилась с ошибками, снимок каталога не обновляем")
 	}
 
+	run.FinishedAt = time.Now()
+
+	for _, item := range c.avitoItems() {
+		if item.Exported {
+			run.Exported++
+		} else {
+			run.Excluded++
+		}
+	}
+	c.saveRun(run)
+
 	c.Clear()
 
 	logger.Log.Logln(logrus.InfoLevel, "Закончили выгрузку")
@@ -134,42 +201,162 @@ func (c *Controller) imageWorker(ctx context.Context, idImageWorker int) {
 	}
 }
 
-// convertProductsToAvito готовит массив Товаров из МойСклад к виду, требуемуму Avito.
-func (c *Controller) convertProductsToAvito(products map[string]storage.Product) []avito.Product {
-	result := make([]avito.Product, 0, len(products))
+// writeBackToMoySklad записывает в доп. поля товаров МойСклад идентификаторы и ссылки объявлений
+// Avito, статус публикации и время выгрузки. Данные объявлений меняются только при изменении,
+// время выгрузки записывается у каждого выгруженного товара.
+func (c *Controller) writeBackToMoySklad(ctx context.Context, run history.Run) {
+	wb := config.Config.WriteBack
+	if !wb.Enabled() {
+		return
+	}
+
+	// Товар может быть в нескольких фидах, в доп. поля записывается объявление первого по
+	// порядку фида, в котором оно есть.
+	feeds := config.Config.AvitoFeeds()
+	ads := make(map[string]report.AvitoStatus)
 
-	for _, p := range products {
-		if len(p.Images) == 0 {
-			logger.Log.Logf(logrus.ErrorLevel, "У товара '%s' не смогли загрузить картинки, убираем его из выгрузки", p.Name)
-			continue
+	for i := len(feeds) - 1; i >= 0; i-- {
+		for id, status := range run.Feeds[feeds[i].Name].Ads {
+			ads[id] = status
 		}
+	}
 
-		p.Description = strings.Join([]string{p.Article, p.Description, config.Config.ProductDescriptionAdd}, "\n")
-
-		product := avito.Product{
-			ID:          p.ID,
-			Title:       p.Name,
-			Description: avito.ProductDescription{Text: p.Description},
-			AvitoId:     p.AvitoId,
-			Price:       p.Price,
-			VideoURL:    p.VideoURL,
-			Address:     "Свердловская обл., Екатеринбург, ул. Хохрякова, 74",
-			Category:    "Коллекционирование",
-			GoodsType:   "Другое",
-			AdType:      "Продаю своё",
-			Condition:   "Новое",
+	exported := make(map[string]bool)
+
+	for _, item := range c.avitoItems() {
+		exported[item.ID] = exported[item.ID] || item.Exported
+	}
+
+	updates := make([]storage.ProductAttributesUpdate, 0)
+
+	for id, isExported := range exported {
+		p, ok := c.storage.Products[id]
+		if !ok {
+			p = c.storage.Excluded[id].Product
 		}
 
-		for _, img := range p.Images {
-			image := avito.Image{
-				Url: img.Url,
+		values := make(map[string]any)
+
+		setValue := func(name, value, current string) {
+			if name != "" && value != "" && value != current {
+				values[name] = value
 			}
+		}
 
-			product.Images.Image = append(product.Images.Image, image)
+		if status, ok := ads[id]; ok {
+			setValue(wb.AvitoId, status.AvitoId, p.Attributes[wb.AvitoId])
+			setValue(wb.AvitoUrl, status.Url, p.Attributes[wb.AvitoUrl])
+			setValue(wb.Status, status.Status, p.Attributes[wb.Status])
 		}
 
-		result = append(result, product)
+		if isExported && wb.ExportedAt != "" {
+			values[wb.ExportedAt] = run.StartedAt
+		}
+
+		if len(values) > 0 {
+			updates = append(updates, storage.ProductAttributesUpdate{ProductID: id, Values: values})
+		}
 	}
 
-	return result
+	if len(updates) == 0 {
+		return
+	}
+
+	if err := c.storage.UpdateProductsAttributes(ctx, updates); err != nil {
+		logger.Log.WithFields(logrus.Fields{
+			"error": err,
+		}).Log(logrus.ErrorLevel, "Ошибка при записи данных Avito в товары МойСклад")
+	}
+}
+
+// avitoFeedNames возвращает имена фидов Avito из настроек.
+func avitoFeedNames() map[string]struct{} {
+	names := make(map[string]struct{})
+	for _, feed := range config.Config.AvitoFeeds() {
+		names[feed.Name] = struct{}{}
+	}
+
+	return names
+}
+
+// avitoItems возвращает строки отчета по фидам Avito и товарам, не прошедшим общий фильтр
+// выгрузки. Результаты маркетплейсов и других выгрузок остаются только в отчете о выгрузке.
+func (c *Controller) avitoItems() []report.Item {
+	feeds := avitoFeedNames()
+	items := make([]report.Item, 0, len(c.report.Items))
+
+	for _, item := range c.report.Items {
+		if _, ok := feeds[item.Feed]; ok || item.Feed == "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+// saveRun добавляет итоги запуска в историю запусков.
+func (c *Controller) saveRun(run history.Run) {
+	runs, err := history.LoadRuns()
+	if err == nil {
+		runs.Add(run)
+		err = runs.Save()
+	}
+
+	if err != nil {
+		logger.Log.WithFields(logrus.Fields{
+			"error": err,
+		}).Log(logrus.ErrorLevel, "Ошибка при сохранении истории запусков")
+	}
+}
+
+// reportExcluded добавляет в отчет товары, не прошедшие общий фильтр выгрузки.
+func (c *Controller) reportExcluded(excluded map[string]storage.ExcludedProduct) {
+	for _, e := range excluded {
+		c.report.Add(export.ReportItem("", e.Product, e.Reason))
+	}
+}
+
+// catalogRows формирует строки каталога для менеджеров из отчета о выгрузке и итоговых
+// записей выгрузок.
+func (c *Controller) catalogRows(listings map[string]map[string]export.Listing) []catalog.Row {
+	items := c.avitoItems()
+	rows := make([]catalog.Row, 0, len(items))
+
+	for _, item := range items {
+		row := catalog.Row{
+			Feed:     item.Feed,
+			ID:       item.ID,
+			Article:  item.Article,
+			Title:    item.Name,
+			Price:    item.Price,
+			Stock:    item.Stock,
+			Exported: item.Exported,
+		}
+
+		if !item.Exported {
+			row.Reason = item.Message
+			if item.Details != "" {
+				row.Reason += ": " + item.Details
+			}
+		}
+
+		if listing, ok := listings[item.Feed][item.ID]; ok {
+			row.Title = listing.Title
+			row.Price = listing.Price
+			row.Category = listing.Category
+			row.Images = listing.Images
+		}
+
+		rows = append(rows, row)
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].Feed != rows[j].Feed {
+			return rows[i].Feed < rows[j].Feed
+		}
+
+		return rows[i].Title < rows[j].Title
+	})
+
+	return rows
 }
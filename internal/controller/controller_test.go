@@ -0,0 +1,42 @@
+package controller
+
+import (
+	"github.com/KirillKhitev/carat_export/internal/config"
+	"github.com/KirillKhitev/carat_export/internal/report"
+	"testing"
+)
+
+func TestMarketplaceRowsStayOutOfAvitoConsumers(t *testing.T) {
+	old := config.Config
+	t.Cleanup(func() { config.Config = old })
+
+	config.Config.Feeds = []config.FeedParams{{Name: "main", FilePath: "main.xml"}, {Name: "second", FilePath: "second.xml"}}
+
+	c := NewController()
+	c.report.Add(report.Item{Feed: "main", ID: "1", Reason: report.ReasonExported})
+	c.report.Add(report.Item{Feed: "second", ID: "2", Reason: report.ReasonNoPrice})
+	c.report.Add(report.Item{ID: "3", Reason: report.ReasonNoExportFlag})
+	c.report.Add(report.Item{Feed: "ozon", ID: "2", Reason: report.ReasonExported})
+	c.report.Add(report.Item{Feed: "wildberries", ID: "4", Reason: report.ReasonNotMatched})
+
+	items := c.avitoItems()
+	if len(items) != 3 {
+		t.Fatalf("items = %+v, want rows of Avito feeds and general filter", items)
+	}
+
+	rows := c.catalogRows(nil)
+	if len(rows) != 3 {
+		t.Fatalf("rows = %+v, want 3 catalog rows", rows)
+	}
+
+	for _, row := range rows {
+		if row.Feed == "ozon" || row.Feed == "wildberries" {
+			t.Fatalf("catalog row of marketplace: %+v", row)
+		}
+	}
+
+	snapshot := c.catalogSnapshot(nil)
+	if len(snapshot.Products) != 1 || len(snapshot.Products[0].Feeds) != 1 || snapshot.Products[0].Feeds[0] != "main" {
+		t.Fatalf("snapshot = %+v, want only product exported to Avito", snapshot.Products)
+	}
+}
@@ -0,0 +1,105 @@
+package controller
+
+import (
+	"github.com/KirillKhitev/carat_export/internal/config"
+	"github.com/KirillKhitev/carat_export/internal/description"
+	"github.com/KirillKhitev/carat_export/internal/export"
+	"github.com/KirillKhitev/carat_export/internal/logger"
+	"github.com/KirillKhitev/carat_export/internal/report"
+	"github.com/KirillKhitev/carat_export/internal/site"
+	"github.com/KirillKhitev/carat_export/internal/storage"
+	"github.com/sirupsen/logrus"
+	"html/template"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxSummaryLength длина описания товара для OpenGraph.
+const maxSummaryLength = 200
+
+// generateSite формирует статический каталог из товаров, выгруженных в фид Avito каталога.
+func (c *Controller) generateSite(listings map[string]map[string]export.Listing) {
+	params := config.Config.Site
+	if !params.Enabled() {
+		return
+	}
+
+	feed := params.Feed
+	if feed == "" {
+		feed = config.Config.AvitoFeeds()[0].Name
+	}
+
+	baseURL := params.BaseURL
+	if baseURL == "" {
+		baseURL = "http://" + config.Config.ServerURL
+	}
+
+	s := site.Site{
+		Title:     params.Title,
+		BaseURL:   strings.TrimSuffix(baseURL, "/") + params.URL,
+		UpdatedAt: time.Now(),
+	}
+
+	renderer := description.NewRenderer()
+
+	for _, item := range c.report.Items {
+		listing, ok := listings[feed][item.ID]
+		if item.Feed != feed || !item.Exported || !ok {
+			continue
+		}
+
+		p := c.storage.Products[item.ID]
+
+		text, err := renderer.Render(p)
+		if err != nil {
+			text = renderer.RenderDefault(p)
+		}
+
+		s.Pages = append(s.Pages, site.Page{
+			ID:     p.ID,
+			Name:   listing.Title,
+			Price:  listing.Price,
+			Folder: p.Folder,
+			Images: listing.Images,
+			// Описание уже очищено от неразрешенных тегов при формировании.
+			Description: template.HTML(text),
+			Summary:     summary(description.ToText(text)),
+			AvitoURL:    avitoURL(item, p),
+		})
+	}
+
+	if err := site.Generate(params.Dir, s); err != nil {
+		logger.Log.WithFields(logrus.Fields{
+			"error": err,
+		}).Log(logrus.ErrorLevel, "Ошибка при формировании каталога товаров")
+
+		return
+	}
+
+	logger.Log.Logf(logrus.InfoLevel, "Сформирован каталог товаров, страниц: %d", len(s.Pages))
+}
+
+// avitoURL возвращает ссылку на объявление: из отчета автозагрузки, а если ее нет - по AvitoId товара.
+func avitoURL(item report.Item, p storage.Product) string {
+	if item.Avito != nil && item.Avito.Url != "" {
+		return item.Avito.Url
+	}
+
+	if p.AvitoId != "" {
+		return "https://www.avito.ru/" + p.AvitoId
+	}
+
+	return ""
+}
+
+// summary обрезает описание до длины, подходящей для превью ссылки.
+func summary(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+
+	if utf8.RuneCountInString(text) <= maxSummaryLength {
+		return text
+	}
+
+	return strings.TrimSpace(string([]rune(text)[:maxSummaryLength-1])) + "…"
+}
@@ -0,0 +1,110 @@
+package controller
+
+import (
+	"context"
+	"github.com/KirillKhitev/carat_export/internal/config"
+	"github.com/KirillKhitev/carat_export/internal/description"
+	"github.com/KirillKhitev/carat_export/internal/export"
+	"github.com/KirillKhitev/carat_export/internal/logger"
+	"github.com/KirillKhitev/carat_export/internal/snapshot"
+	"github.com/KirillKhitev/carat_export/internal/telegram"
+	"github.com/sirupsen/logrus"
+	"slices"
+	"sort"
+	"time"
+)
+
+// catalogSnapshot формирует снимок выгруженного каталога. В снимок попадают товары,
+// выгруженные хотя бы в одну выгрузку. Название, цена и изображения берутся из основного
+// фида Avito, а если товара в нем нет - из первой выгрузки с товаром.
+func (c *Controller) catalogSnapshot(listings map[string]map[string]export.Listing) snapshot.Snapshot {
+	mainFeed := config.Config.AvitoFeeds()[0].Name
+	products := make(map[string]*snapshot.Product)
+	renderer := description.NewRenderer()
+
+	for _, item := range c.avitoItems() {
+		if !item.Exported {
+			continue
+		}
+
+		product, ok := products[item.ID]
+		if !ok {
+			p := c.storage.Products[item.ID]
+
+			text, err := renderer.Render(p)
+			if err != nil {
+				text = renderer.RenderDefault(p)
+			}
+
+			product = &snapshot.Product{
+				ID:          p.ID,
+				Name:        p.Name,
+				Article:     p.Article,
+				Folder:      p.Folder,
+				Price:       item.Price,
+				Stock:       p.Stock,
+				Description: text,
+				Images:      make([]string, 0),
+			}
+
+			products[item.ID] = product
+		}
+
+		product.Feeds = append(product.Feeds, item.Feed)
+
+		if url := avitoURL(item, c.storage.Products[item.ID]); url != "" && product.AvitoURL == "" {
+			product.AvitoURL = url
+		}
+
+		listing, ok := listings[item.Feed][item.ID]
+		if !ok || (len(product.Feeds) > 1 && item.Feed != mainFeed) {
+			continue
+		}
+
+		product.Name = listing.Title
+		product.Price = listing.Price
+		product.Images = slices.Clone(listing.Images)
+	}
+
+	s := snapshot.Snapshot{
+		CreatedAt: time.Now(),
+		Products:  make([]snapshot.Product, 0, len(products)),
+	}
+
+	for _, p := range products {
+		s.Products = append(s.Products, *p)
+	}
+
+	sort.Slice(s.Products, func(i, j int) bool {
+		return s.Products[i].Name < s.Products[j].Name
+	})
+
+	return s
+}
+
+// saveSnapshot сохраняет снимок каталога для API каталога.
+func (c *Controller) saveSnapshot(s snapshot.Snapshot) {
+	if err := s.Save(snapshot.Path()); err != nil {
+		logger.Log.WithFields(logrus.Fields{
+			"error": err,
+		}).Log(logrus.ErrorLevel, "Ошибка при сохранении снимка каталога")
+	}
+}
+
+// publishTelegram публикует в канале Telegram товары, впервые попавшие в выгрузку, если это настроено.
+func (c *Controller) publishTelegram(ctx context.Context, s snapshot.Snapshot) {
+	if !telegram.Enabled() {
+		return
+	}
+
+	publisher, err := telegram.NewPublisher()
+	if err == nil {
+		err = publisher.Publish(ctx, s)
+	}
+
+	if err != nil {
+		logger.Log.WithFields(logrus.Fields{
+			"error": err,
+		}).Log(logrus.ErrorLevel, "Ошибка при публикации новых товаров в Telegram")
+	}
+}
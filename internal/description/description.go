@@ -0,0 +1,100 @@
+package description
+
+import (
+	"fmt"
+	"github.com/KirillKhitev/carat_export/internal/config"
+	"github.com/KirillKhitev/carat_export/internal/storage"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// defaultTemplate повторяет прежнее описание: артикул, описание из МойСклад и общий текст.
+const defaultTemplate = `{{.Article}}
+{{.Description}}
+{{.DescriptionAdd}}`
+
+// Data данные, доступные в шаблоне описания.
+type Data struct {
+	storage.Product
+	DescriptionAdd string
+}
+
+// Attr возвращает значение доп. поля товара по его названию в МойСклад.
+func (d Data) Attr(name string) string {
+	return d.Attributes[name]
+}
+
+// Renderer формирует описания товаров по шаблонам, кешируя разобранные шаблоны.
+type Renderer struct {
+	templates map[string]*template.Template
+}
+
+func NewRenderer() *Renderer {
+	return &Renderer{
+		templates: make(map[string]*template.Template),
+	}
+}
+
+// Render формирует описание товара в HTML, допустимом в Avito. Шаблон берется из настроек группы
+// товара, затем из общих настроек, иначе используется шаблон по умолчанию.
+func (r *Renderer) Render(p storage.Product) (string, error) {
+	path := config.Config.Folder(p.Folder).DescriptionTemplate
+	if path == "" {
+		path = config.Config.DescriptionTemplate
+	}
+
+	tmpl, err := r.template(path)
+	if err != nil {
+		return "", err
+	}
+
+	data := Data{
+		Product:        p,
+		DescriptionAdd: config.Config.ProductDescriptionAdd,
+	}
+
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return "", fmt.Errorf("ошибка шаблона описания %s: %w", path, err)
+	}
+
+	return ToHTML(sb.String()), nil
+}
+
+// RenderDefault формирует описание по шаблону по умолчанию.
+func (r *Renderer) RenderDefault(p storage.Product) string {
+	tmpl, _ := r.template("")
+
+	var sb strings.Builder
+	tmpl.Execute(&sb, Data{Product: p, DescriptionAdd: config.Config.ProductDescriptionAdd})
+
+	return ToHTML(sb.String())
+}
+
+// template возвращает разобранный шаблон из файла path, пустой path - шаблон по умолчанию.
+func (r *Renderer) template(path string) (*template.Template, error) {
+	if tmpl, ok := r.templates[path]; ok {
+		return tmpl, nil
+	}
+
+	text := defaultTemplate
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("не удалось прочитать шаблон описания %s: %w", path, err)
+		}
+
+		text = string(data)
+	}
+
+	tmpl, err := template.New(path).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось разобрать шаблон описания %s: %w", path, err)
+	}
+
+	r.templates[path] = tmpl
+
+	return tmpl, nil
+}
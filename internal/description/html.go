@@ -0,0 +1,153 @@
+package description
+
+import (
+	"html"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// allowedTags теги, которые Avito допускает в описании объявления.
+var allowedTags = []string{"p", "br", "ul", "li", "strong", "em"}
+
+var (
+	tagRegexp       = regexp.MustCompile(`<\s*(/?)\s*([a-zA-Z][a-zA-Z0-9]*)[^<>]*>`)
+	listItemRegexp  = regexp.MustCompile(`^\s*(?:[-*•–])\s+`)
+	blankLineRegexp = regexp.MustCompile(`\n\s*\n`)
+)
+
+// ToHTML превращает текст из МойСклад в HTML для Avito: абзацы разделяются пустой строкой,
+// переносы строк становятся <br>, строки, начинающиеся с "-", "*" или "•", - списком.
+// Теги из списка разрешенных сохраняются, остальные удаляются, спецсимволы экранируются.
+func ToHTML(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+
+	blocks := make([]string, 0)
+
+	for _, block := range blankLineRegexp.Split(text, -1) {
+		lines := nonEmptyLines(block)
+		if len(lines) == 0 {
+			continue
+		}
+
+		blocks = append(blocks, blockToHTML(lines))
+	}
+
+	return strings.Join(blocks, "\n")
+}
+
+// blockToHTML оформляет абзац текста в абзац или список.
+func blockToHTML(lines []string) string {
+	if slices.IndexFunc(lines, func(l string) bool { return !listItemRegexp.MatchString(l) }) == -1 {
+		items := make([]string, 0, len(lines))
+		for _, l := range lines {
+			items = append(items, "<li>"+Sanitize(listItemRegexp.ReplaceAllString(l, ""))+"</li>")
+		}
+
+		return "<ul>" + strings.Join(items, "") + "</ul>"
+	}
+
+	joined := Sanitize(strings.Join(lines, "<br>"))
+
+	if strings.HasPrefix(joined, "<p>") || strings.HasPrefix(joined, "<ul>") {
+		return joined
+	}
+
+	return "<p>" + joined + "</p>"
+}
+
+// Sanitize оставляет в тексте только разрешенные теги без атрибутов и экранирует остальной текст.
+// Закрывающие теги без открывающих удаляются, незакрытые теги закрываются в конце текста.
+func Sanitize(text string) string {
+	var sb strings.Builder
+
+	open := make([]string, 0)
+	last := 0
+
+	for _, m := range tagRegexp.FindAllStringSubmatchIndex(text, -1) {
+		sb.WriteString(html.EscapeString(html.UnescapeString(text[last:m[0]])))
+		last = m[1]
+
+		closing := text[m[2]:m[3]] == "/"
+		name := strings.ToLower(text[m[4]:m[5]])
+
+		if !slices.Contains(allowedTags, name) {
+			continue
+		}
+
+		switch {
+		case name == "br":
+			sb.WriteString("<br>")
+		case closing:
+			i := len(open) - 1
+			for i >= 0 && open[i] != name {
+				i--
+			}
+
+			if i == -1 {
+				continue
+			}
+
+			for len(open) > i {
+				sb.WriteString("</" + open[len(open)-1] + ">")
+				open = open[:len(open)-1]
+			}
+		default:
+			sb.WriteString("<" + name + ">")
+			open = append(open, name)
+		}
+	}
+
+	sb.WriteString(html.EscapeString(html.UnescapeString(text[last:])))
+
+	for i := len(open) - 1; i >= 0; i-- {
+		sb.WriteString("</" + open[i] + ">")
+	}
+
+	return sb.String()
+}
+
+// ToText превращает HTML описание в простой текст для площадок, которые не принимают разметку:
+// абзацы, переносы и пункты списка становятся переносами строк, теги удаляются.
+func ToText(text string) string {
+	var sb strings.Builder
+
+	last := 0
+
+	for _, m := range tagRegexp.FindAllStringSubmatchIndex(text, -1) {
+		sb.WriteString(text[last:m[0]])
+		last = m[1]
+
+		closing := text[m[2]:m[3]] == "/"
+
+		switch name := strings.ToLower(text[m[4]:m[5]]); {
+		case name == "br" || (closing && name == "li"):
+			sb.WriteString("\n")
+		case closing && (name == "p" || name == "ul"):
+			sb.WriteString("\n\n")
+		case !closing && name == "li":
+			sb.WriteString("- ")
+		}
+	}
+
+	sb.WriteString(text[last:])
+
+	lines := strings.Split(html.UnescapeString(sb.String()), "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimSpace(l)
+	}
+
+	return strings.TrimSpace(blankLineRegexp.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
+
+func nonEmptyLines(block string) []string {
+	result := make([]string, 0)
+
+	for _, l := range strings.Split(block, "\n") {
+		if l = strings.TrimSpace(l); l != "" {
+			result = append(result, l)
+		}
+	}
+
+	return result
+}
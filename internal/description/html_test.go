@@ -0,0 +1,58 @@
+package description
+
+import "testing"
+
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"plain text", "Монета 1 унция", "Монета 1 унция"},
+		{"special chars are escaped", `Вес < 5 г & "проба"`, "Вес &lt; 5 г &amp; &#34;проба&#34;"},
+		{"entities are not escaped twice", "Tom &amp; Jerry", "Tom &amp; Jerry"},
+		{"allowed tag keeps no attributes", `<strong class="x">Серебро</strong>`, "<strong>Серебро</strong>"},
+		{"tags are lowercased", "<EM>курсив</EM>", "<em>курсив</em>"},
+		{"forbidden tags are removed", `<a href="http://x">ссылка</a><script>alert(1)</script>`, "ссылкаalert(1)"},
+		{"br is never closed", "строка<br/>строка<BR>", "строка<br>строка<br>"},
+		{"unclosed tag is closed at the end", "<strong>Серебро", "<strong>Серебро</strong>"},
+		{"nested unclosed tags are closed in order", "<ul><li>один", "<ul><li>один</li></ul>"},
+		{"unmatched closing tag is dropped", "Серебро</strong> 999", "Серебро 999"},
+		{"closing outer tag closes inner", "<p><em>курсив</p> текст", "<p><em>курсив</em></p> текст"},
+		{"closing tag matches nearest open", "<em>a<em>b</em>c", "<em>a<em>b</em>c</em>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Sanitize(tt.text); got != tt.want {
+				t.Errorf("Sanitize(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToHTML(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"empty", "  \n\n ", ""},
+		{"paragraph", "Монета", "<p>Монета</p>"},
+		{"line breaks", "Серебро\r\nПроба 999", "<p>Серебро<br>Проба 999</p>"},
+		{"paragraphs", "Первый\n\n  \nВторой", "<p>Первый</p>\n<p>Второй</p>"},
+		{"list", "- серебро\n* 1 унция\n• 2024 год", "<ul><li>серебро</li><li>1 унция</li><li>2024 год</li></ul>"},
+		{"mixed block is a paragraph", "Состав:\n- серебро", "<p>Состав:<br>- серебро</p>"},
+		{"existing paragraph is kept", "<p>Готовый HTML</p>", "<p>Готовый HTML</p>"},
+		{"tag across lines is balanced", "<strong>Серебро\nпроба</strong>", "<p><strong>Серебро<br>проба</strong></p>"},
+		{"unclosed tag in list item", "- <em>серебро", "<ul><li><em>серебро</em></li></ul>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ToHTML(tt.text); got != tt.want {
+				t.Errorf("ToHTML(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,421 @@
+package export
+
+import (
+	"context"
+	"github.com/KirillKhitev/carat_export/internal/avito"
+	"github.com/KirillKhitev/carat_export/internal/avitoapi"
+	"github.com/KirillKhitev/carat_export/internal/config"
+	"github.com/KirillKhitev/carat_export/internal/description"
+	"github.com/KirillKhitev/carat_export/internal/history"
+	"github.com/KirillKhitev/carat_export/internal/images"
+	"github.com/KirillKhitev/carat_export/internal/logger"
+	"github.com/KirillKhitev/carat_export/internal/report"
+	"github.com/KirillKhitev/carat_export/internal/storage"
+	"github.com/KirillKhitev/carat_export/internal/textutil"
+	"github.com/KirillKhitev/carat_export/internal/timeutil"
+	"github.com/sirupsen/logrus"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Значения полей объявления, если они не заданы ни у товара, ни в настройках.
+const (
+	defaultAddress   = "Свердловская обл., Екатеринбург, ул. Хохрякова, 74"
+	defaultAdType    = "Продаю своё"
+	defaultCondition = "Новое"
+	defaultCategory  = "Коллекционирование"
+	defaultGoodsType = "Другое"
+)
+
+// FormatAvito формат файла автозагрузки Avito. Для каждого фида из настроек создается своя выгрузка.
+const FormatAvito = "avito"
+
+func init() {
+	Register(FormatAvito, newAvitoExporters)
+}
+
+// avitoExporter выгрузка фида Avito.
+type avitoExporter struct {
+	feed     config.FeedParams
+	history  *history.Store
+	products []avito.Product
+}
+
+func newAvitoExporters() []Exporter {
+	feeds := config.Config.AvitoFeeds()
+	result := make([]Exporter, 0, len(feeds))
+
+	for _, feed := range feeds {
+		result = append(result, &avitoExporter{feed: feed})
+	}
+
+	return result
+}
+
+func (e *avitoExporter) Name() string {
+	return e.feed.Name
+}
+
+func (e *avitoExporter) FilePath() string {
+	return e.feed.FilePath
+}
+
+func (e *avitoExporter) ServePath() string {
+	return e.feed.URL
+}
+
+// Convert формирует объявления фида. Без истории выгрузки снятые с продажи объявления не закрываются.
+func (e *avitoExporter) Convert(ctx context.Context, run *Run) error {
+	exportHistory, err := history.Load(e.feed.Name)
+	if err != nil {
+		logger.Log.WithFields(logrus.Fields{
+			"error": err,
+			"feed":  e.feed.Name,
+		}).Log(logrus.ErrorLevel, "Ошибка при чтении истории выгрузки, снятые с продажи объявления не будут закрыты")
+	}
+
+	e.history = exportHistory
+	e.products = convertProductsToAvito(e.feed, run.Products, exportHistory, run.Report)
+
+	return nil
+}
+
+// Validate проверяет объявления перед публикацией и отмечает найденные проблемы в отчете.
+func (e *avitoExporter) Validate(ctx context.Context, run *Run) error {
+	valid, issues := avito.Validate(e.products)
+
+	for _, issue := range issues {
+		if issue.Fixed {
+			run.Report.Warn(e.feed.Name, issue.ID, issue.String())
+		} else {
+			run.Report.Exclude(e.feed.Name, issue.ID, report.ReasonValidationErr, issue.String())
+		}
+	}
+
+	if len(issues) > 0 {
+		logger.Log.WithFields(logrus.Fields{
+			"issues": issues,
+		}).Logf(logrus.WarnLevel, "Проверка объявлений Avito фида %s: исключено %d из %d", e.feed.Name, len(e.products)-len(valid), len(e.products))
+	}
+
+	e.products = valid
+
+	for _, p := range valid {
+		listing := Listing{Title: p.Title, Price: p.Price, Category: p.Category}
+		for _, img := range p.Images.Image {
+			listing.Images = append(listing.Images, img.Url)
+		}
+
+		run.AddListing(e.feed.Name, p.ID, listing)
+	}
+
+	return nil
+}
+
+// Write сохраняет файл автозагрузки вместе с закрываемыми объявлениями, историю выгрузки и
+// получает последний отчет автозагрузки аккаунта фида.
+func (e *avitoExporter) Write(ctx context.Context, run *Run) error {
+	products := e.products
+
+	if e.history != nil {
+		products = append(products, closeRemovedProducts(e.history, products)...)
+	}
+
+	if err := avito.CreateAutoloadFile(e.feed.FilePath, products); err != nil {
+		return err
+	}
+
+	if e.history != nil {
+		if err := e.history.Save(); err != nil {
+			logger.Log.WithFields(logrus.Fields{
+				"error": err,
+				"feed":  e.feed.Name,
+			}).Log(logrus.ErrorLevel, "Ошибка при сохранении истории выгрузки")
+		}
+	}
+
+	ingestAutoloadReport(ctx, e.feed, run)
+
+	return nil
+}
+
+// convertProductsToAvito готовит массив Товаров из МойСклад к виду, требуемуму Avito, для фида:
+// отбирает товары по условиям фида, берет цену нужного типа и значения полей по умолчанию фида.
+func convertProductsToAvito(feed config.FeedParams, products map[string]storage.Product, exportHistory *history.Store, r *report.Report) []avito.Product {
+	result := make([]avito.Product, 0, len(products))
+	renderer := description.NewRenderer()
+	imagesParams := feed.ImagesParams(config.Config.Images)
+	now := time.Now()
+
+	for _, p := range products {
+		p.Price = p.PriceByType(feed.PriceType)
+
+		if reason := feedExcludeReason(feed, p); reason != "" {
+			r.Add(ReportItem(feed.Name, p, reason))
+			continue
+		}
+
+		productImages := images.Prepare(p, imagesParams)
+
+		if len(productImages) == 0 {
+			reason := report.ReasonImagesFailed
+			if p.ImagesResponse.Meta.Size == 0 {
+				reason = report.ReasonNoImages
+			} else {
+				logger.Log.Logf(logrus.ErrorLevel, "У товара '%s' не смогли загрузить картинки, убираем его из выгрузки", p.Name)
+			}
+
+			r.Add(ReportItem(feed.Name, p, reason))
+			continue
+		}
+
+		text, err := renderer.Render(p)
+		if err != nil {
+			logger.Log.WithFields(logrus.Fields{
+				"error":     err,
+				"productId": p.ID,
+			}).Log(logrus.ErrorLevel, "Ошибка формирования описания товара, используем описание по умолчанию")
+
+			text = renderer.RenderDefault(p)
+		}
+
+		folder := config.Config.Folder(p.Folder)
+		defaults := feed.Defaults
+		firstExportedAt, ok := exportHistory.FirstExportedAt(p.ID)
+		if !ok {
+			firstExportedAt = now
+		}
+
+		promotion := matchPromotionRule(p, now.Sub(firstExportedAt))
+
+		product := avito.Product{
+			ID:          p.ID,
+			Title:       p.Name,
+			Description: avito.ProductDescription{Text: text},
+			AvitoId:     p.AvitoId,
+			Price:       p.Price,
+			VideoURL:    p.VideoURL,
+			Address:     textutil.FirstNotEmpty(p.Address, storeAddress(p), folder.Address, defaults.Address, config.Config.AvitoAddress, defaultAddress),
+			Category:    textutil.FirstNotEmpty(folder.Category, defaults.Category, config.Config.AvitoCategory, defaultCategory),
+			GoodsType:   textutil.FirstNotEmpty(folder.GoodsType, defaults.GoodsType, config.Config.AvitoGoodsType, defaultGoodsType),
+			AdType:      textutil.FirstNotEmpty(p.AdType, folder.AdType, defaults.AdType, config.Config.AvitoAdType, defaultAdType),
+			Condition:   textutil.FirstNotEmpty(p.Condition, folder.Condition, defaults.Condition, config.Config.AvitoCondition, defaultCondition),
+
+			ContactPhone:  textutil.FirstNotEmpty(p.ContactPhone, folder.ContactPhone, defaults.ContactPhone, config.Config.AvitoContactPhone),
+			ManagerName:   textutil.FirstNotEmpty(p.ManagerName, folder.ManagerName, defaults.ManagerName, config.Config.AvitoManagerName),
+			ContactMethod: textutil.FirstNotEmpty(p.ContactMethod, folder.ContactMethod, defaults.ContactMethod, config.Config.AvitoContactMethod),
+			ListingFee:    textutil.FirstNotEmpty(p.ListingFee, promotion.ListingFee, folder.ListingFee, defaults.ListingFee, config.Config.AvitoListingFee),
+			AdStatus:      textutil.FirstNotEmpty(p.AdStatus, promotion.AdStatus, folder.AdStatus, defaults.AdStatus, config.Config.AvitoAdStatus),
+		}
+
+		applySchedule(&product, p, now)
+		avito.ApplySchema(&product, p.Attributes)
+
+		for _, img := range productImages {
+			image := avito.Image{
+				Url: img.Url,
+			}
+
+			product.Images.Image = append(product.Images.Image, image)
+		}
+
+		result = append(result, product)
+		r.Add(ReportItem(feed.Name, p, report.ReasonExported))
+	}
+
+	return result
+}
+
+// feedExcludeReason возвращает причину, по которой товар не подходит под условия фида.
+// Цена товара уже должна быть взята по типу цены фида.
+func feedExcludeReason(feed config.FeedParams, p storage.Product) report.Reason {
+	switch {
+	case !feed.Match(p.Folder):
+		return report.ReasonFeedFilter
+	case p.Price == 0:
+		return report.ReasonNoPrice
+	case feed.MinPrice > 0 && p.Price < feed.MinPrice:
+		return report.ReasonFeedFilter
+	case feed.MinStock > 0 && p.Stock < feed.MinStock:
+		return report.ReasonFeedFilter
+	}
+
+	return ""
+}
+
+// closeRemovedProducts возвращает ранее выгруженные объявления, товары которых пропали из выгрузки.
+// Такие объявления остаются в файле с DateEnd, чтобы Avito корректно их закрыл.
+func closeRemovedProducts(exportHistory *history.Store, products []avito.Product) []avito.Product {
+	keep := time.Hour * 24 * time.Duration(config.Config.AvitoRemovedKeepDays)
+
+	removed := exportHistory.Update(products, time.Now(), keep)
+
+	logger.Log.WithFields(logrus.Fields{
+		"products": removed,
+	}).Logf(logrus.InfoLevel, "Закрываем объявления снятых с продажи товаров: %d", len(removed))
+
+	return removed
+}
+
+// ingestAutoloadReport получает последний отчет автозагрузки аккаунта Avito фида и сопоставляет
+// ошибки и предупреждения по объявлениям с товарами МойСклад: Id объявления совпадает с ID товара.
+func ingestAutoloadReport(ctx context.Context, feed config.FeedParams, run *Run) {
+	avitoApi := avitoapi.NewFeedClient(feed)
+	if !avitoApi.Enabled() {
+		return
+	}
+
+	autoloadReport, err := avitoApi.LastCompletedReport(ctx)
+	if err != nil {
+		logger.Log.WithFields(logrus.Fields{
+			"error": err,
+			"feed":  feed.Name,
+		}).Log(logrus.ErrorLevel, "Ошибка при получении отчета автозагрузки Avito")
+
+		return
+	}
+
+	items, err := avitoApi.ReportItems(ctx, autoloadReport.ReportId)
+	if err != nil {
+		logger.Log.WithFields(logrus.Fields{
+			"error":    err,
+			"reportId": autoloadReport.ReportId,
+			"feed":     feed.Name,
+		}).Log(logrus.ErrorLevel, "Ошибка при получении объявлений из отчета автозагрузки Avito")
+
+		return
+	}
+
+	feedRun := history.FeedRun{
+		AutoloadReportId: autoloadReport.ReportId,
+		Ads:              make(map[string]report.AvitoStatus, len(items)),
+	}
+
+	for _, item := range items {
+		status := avitoStatus(item)
+
+		feedRun.Ads[item.AdId] = status
+		run.Report.SetAvitoStatus(feed.Name, item.AdId, status)
+	}
+
+	run.History.SetFeed(feed.Name, feedRun)
+
+	logger.Log.Logf(logrus.InfoLevel, "Получили отчет автозагрузки Avito #%d фида %s, объявлений: %d", autoloadReport.ReportId, feed.Name, len(items))
+}
+
+// avitoStatus переводит результат обработки объявления из отчета Avito в строку отчета о выгрузке.
+func avitoStatus(item avitoapi.ReportItem) report.AvitoStatus {
+	status := report.AvitoStatus{
+		Url:    item.Url,
+		Status: item.AvitoStatus,
+	}
+
+	if item.AvitoId != 0 {
+		status.AvitoId = strconv.FormatInt(item.AvitoId, 10)
+	}
+
+	for _, m := range item.Messages {
+		switch m.Type {
+		case avitoapi.MessageError:
+			status.Errors = append(status.Errors, m.String())
+		case avitoapi.MessageWarning:
+			status.Warnings = append(status.Warnings, m.String())
+		}
+	}
+
+	return status
+}
+
+// applySchedule заполняет даты начала и окончания публикации: из доп. полей товара, а если они
+// не заданы - по сезонному правилу группы товара.
+func applySchedule(product *avito.Product, p storage.Product, now time.Time) {
+	begin, end := p.DateBegin, p.DateEnd
+
+	for _, rule := range config.Config.ScheduleRules {
+		if p.Folder != rule.Folder && !strings.HasPrefix(p.Folder, rule.Folder+"/") {
+			continue
+		}
+
+		seasonBegin, seasonEnd, err := timeutil.Season{From: rule.From, To: rule.To}.Window(now)
+		if err != nil {
+			logger.Log.WithFields(logrus.Fields{
+				"error": err,
+				"rule":  rule,
+			}).Log(logrus.ErrorLevel, "Ошибка в сезонном правиле публикации")
+
+			continue
+		}
+
+		if begin.IsZero() {
+			begin = seasonBegin
+		}
+
+		if end.IsZero() {
+			end = seasonEnd
+		}
+
+		break
+	}
+
+	if !begin.IsZero() {
+		product.DateBegin = avito.FormatDate(begin)
+	}
+
+	if !end.IsZero() {
+		product.DateEnd = avito.FormatDate(end)
+	}
+}
+
+// matchPromotionRule возвращает первое правило продвижения, подходящее товару.
+// age - сколько времени прошло с первой выгрузки товара.
+func matchPromotionRule(p storage.Product, age time.Duration) config.PromotionRule {
+	for _, rule := range config.Config.PromotionRules {
+		if rule.Folder != "" && p.Folder != rule.Folder && !strings.HasPrefix(p.Folder, rule.Folder+"/") {
+			continue
+		}
+
+		if rule.MinPrice > 0 && p.Price < rule.MinPrice {
+			continue
+		}
+
+		if rule.MaxPrice > 0 && p.Price > rule.MaxPrice {
+			continue
+		}
+
+		if rule.NewDays > 0 && age > time.Hour*24*time.Duration(rule.NewDays) {
+			continue
+		}
+
+		return rule
+	}
+
+	return config.PromotionRule{}
+}
+
+// storeAddress возвращает адрес склада, на котором есть товар. Если товар лежит на нескольких
+// складах с настроенным адресом, выбирается склад с наибольшим остатком.
+func storeAddress(p storage.Product) string {
+	names := make([]string, 0, len(p.Stores))
+	for name := range p.Stores {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	address := ""
+	var maxStock float32
+
+	for _, name := range names {
+		a, ok := config.Config.StoreAddresses[name]
+		if !ok || p.Stores[name] <= maxStock {
+			continue
+		}
+
+		address = a
+		maxStock = p.Stores[name]
+	}
+
+	return address
+}
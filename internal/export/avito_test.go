@@ -0,0 +1,86 @@
+package export
+
+import (
+	"github.com/KirillKhitev/carat_export/internal/avito"
+	"github.com/KirillKhitev/carat_export/internal/config"
+	"github.com/KirillKhitev/carat_export/internal/storage"
+	"github.com/KirillKhitev/carat_export/internal/timeutil"
+	"testing"
+	"time"
+)
+
+func TestApplySchedule(t *testing.T) {
+	old := config.Config
+	t.Cleanup(func() { config.Config = old })
+
+	config.Config.ScheduleRules = []config.ScheduleRule{
+		{Folder: "Елочные игрушки", From: "12-01", To: "01-15"},
+		{Folder: "Сломанное", From: "99-99", To: "01-15"},
+	}
+
+	// 30 ноября 21:30 UTC - уже 1 декабря в Москве.
+	now := time.Date(2024, time.November, 30, 21, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		product   storage.Product
+		wantBegin string
+		wantEnd   string
+	}{
+		{
+			name:    "no dates and no rule",
+			product: storage.Product{Folder: "Монеты"},
+		},
+		{
+			name: "dates from attributes are in moscow time",
+			product: storage.Product{
+				Folder:    "Монеты",
+				DateBegin: time.Date(2024, time.May, 1, 10, 0, 0, 0, timeutil.Moscow),
+				DateEnd:   time.Date(2024, time.May, 9, 7, 0, 0, 0, time.UTC),
+			},
+			wantBegin: "2024-05-01T10:00:00+03:00",
+			wantEnd:   "2024-05-09T10:00:00+03:00",
+		},
+		{
+			name:      "season rule of folder",
+			product:   storage.Product{Folder: "Елочные игрушки"},
+			wantBegin: "2024-12-01T00:00:00+03:00",
+			wantEnd:   "2025-01-15T23:59:59+03:00",
+		},
+		{
+			name:      "season rule of parent folder",
+			product:   storage.Product{Folder: "Елочные игрушки/Стекло"},
+			wantBegin: "2024-12-01T00:00:00+03:00",
+			wantEnd:   "2025-01-15T23:59:59+03:00",
+		},
+		{
+			name:    "folder with similar name",
+			product: storage.Product{Folder: "Елочные игрушки СССР"},
+		},
+		{
+			name: "attribute date has priority over season",
+			product: storage.Product{
+				Folder:  "Елочные игрушки",
+				DateEnd: time.Date(2024, time.December, 31, 18, 0, 0, 0, timeutil.Moscow),
+			},
+			wantBegin: "2024-12-01T00:00:00+03:00",
+			wantEnd:   "2024-12-31T18:00:00+03:00",
+		},
+		{
+			name:    "broken rule is skipped",
+			product: storage.Product{Folder: "Сломанное"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var product avito.Product
+
+			applySchedule(&product, tt.product, now)
+
+			if product.DateBegin != tt.wantBegin || product.DateEnd != tt.wantEnd {
+				t.Fatalf("dates = %q - %q, want %q - %q", product.DateBegin, product.DateEnd, tt.wantBegin, tt.wantEnd)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package export
+
+import (
+	"context"
+	"fmt"
+	"github.com/KirillKhitev/carat_export/internal/config"
+	"github.com/KirillKhitev/carat_export/internal/history"
+	"github.com/KirillKhitev/carat_export/internal/report"
+	"github.com/KirillKhitev/carat_export/internal/storage"
+)
+
+// Exporter выгрузка каталога МойСклад в формате площадки. Выгрузка выполняется по шагам:
+// Convert формирует записи из товаров МойСклад, Validate проверяет их, Write сохраняет файл.
+// Результаты по товарам отмечаются в отчете запуска под именем выгрузки.
+type Exporter interface {
+	// Name имя выгрузки, под которым она попадает в отчет.
+	Name() string
+	// FilePath путь до файла выгрузки. Пустой путь - выгрузка отправляется через API площадки.
+	FilePath() string
+	// ServePath путь, по которому файл выгрузки отдается HTTP сервером. Пустой путь - файла нет.
+	ServePath() string
+	Convert(ctx context.Context, run *Run) error
+	Validate(ctx context.Context, run *Run) error
+	Write(ctx context.Context, run *Run) error
+}
+
+// Listing итоговая запись товара в выгрузке - то, что увидят покупатели на площадке.
+type Listing struct {
+	Title    string
+	Price    int
+	Category string
+	Images   []string
+}
+
+// Run данные одного запуска выгрузки, общие для всех форматов.
+type Run struct {
+	Products map[string]storage.Product
+	// AllProducts все товары МойСклад, включая исключенные из выгрузки, для обнуления остатков.
+	AllProducts map[string]storage.Product
+	// StoresLoaded показывает, что остатки товаров по складам получены.
+	StoresLoaded bool
+	Report       *report.Report
+	History      *history.Run
+	// Listings итоговые записи выгрузок: имя выгрузки -> ID товара -> запись.
+	Listings map[string]map[string]Listing
+}
+
+func NewRun(products map[string]storage.Product, r *report.Report, h *history.Run) *Run {
+	return &Run{
+		Products: products,
+		Report:   r,
+		History:  h,
+		Listings: make(map[string]map[string]Listing),
+	}
+}
+
+// AddListing запоминает итоговую запись товара в выгрузке.
+func (r *Run) AddListing(name string, id string, listing Listing) {
+	if r.Listings[name] == nil {
+		r.Listings[name] = make(map[string]Listing)
+	}
+
+	r.Listings[name][id] = listing
+}
+
+// Factory создает выгрузки формата по настройкам. Пустой результат - формат не настроен.
+type Factory func() []Exporter
+
+var (
+	registry = make(map[string]Factory)
+	formats  = make([]string, 0)
+)
+
+// Register добавляет формат выгрузки. Форматы выполняются в порядке регистрации.
+func Register(format string, factory Factory) {
+	if _, ok := registry[format]; !ok {
+		formats = append(formats, format)
+	}
+
+	registry[format] = factory
+}
+
+// Formats возвращает имена зарегистрированных форматов.
+func Formats() []string {
+	return append([]string(nil), formats...)
+}
+
+// Enabled возвращает выгрузки всех форматов, включенных в настройках.
+func Enabled() []Exporter {
+	result := make([]Exporter, 0)
+
+	for _, format := range formats {
+		if config.Config.ExportEnabled(format) {
+			result = append(result, registry[format]()...)
+		}
+	}
+
+	return result
+}
+
+// Export выполняет все шаги выгрузки.
+func Export(ctx context.Context, e Exporter, run *Run) error {
+	if err := e.Convert(ctx, run); err != nil {
+		return fmt.Errorf("ошибка подготовки выгрузки %s: %w", e.Name(), err)
+	}
+
+	if err := e.Validate(ctx, run); err != nil {
+		return fmt.Errorf("ошибка проверки выгрузки %s: %w", e.Name(), err)
+	}
+
+	if err := e.Write(ctx, run); err != nil {
+		return fmt.Errorf("ошибка сохранения выгрузки %s: %w", e.Name(), err)
+	}
+
+	return nil
+}
+
+// addReasons добавляет в отчет результаты выгрузки name по товарам запуска.
+func addReasons(run *Run, name string, reasons map[string]report.Reason) {
+	for id, reason := range reasons {
+		if p, ok := run.Products[id]; ok {
+			run.Report.Add(ReportItem(name, p, reason))
+		}
+	}
+}
+
+// ReportItem формирует строку отчета по товару МойСклад в выгрузке.
+func ReportItem(name string, p storage.Product, reason report.Reason) report.Item {
+	return report.Item{
+		Feed:    name,
+		ID:      p.ID,
+		Name:    p.Name,
+		Article: p.Article,
+		Price:   p.Price,
+		Stock:   p.Stock,
+		Reason:  reason,
+	}
+}
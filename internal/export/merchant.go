@@ -0,0 +1,165 @@
+package export
+
+import (
+	"context"
+	"fmt"
+	"github.com/KirillKhitev/carat_export/internal/config"
+	"github.com/KirillKhitev/carat_export/internal/description"
+	"github.com/KirillKhitev/carat_export/internal/images"
+	"github.com/KirillKhitev/carat_export/internal/logger"
+	"github.com/KirillKhitev/carat_export/internal/merchant"
+	"github.com/KirillKhitev/carat_export/internal/report"
+	"github.com/KirillKhitev/carat_export/internal/storage"
+	"github.com/KirillKhitev/carat_export/internal/textutil"
+	"github.com/sirupsen/logrus"
+	"sort"
+	"strings"
+)
+
+// FormatGoogleMerchant формат RSS фида Google Merchant Center.
+const FormatGoogleMerchant = "google_merchant"
+
+func init() {
+	Register(FormatGoogleMerchant, func() []Exporter {
+		if !config.Config.GoogleMerchant.Enabled() {
+			return nil
+		}
+
+		return []Exporter{&merchantExporter{params: config.Config.GoogleMerchant}}
+	})
+}
+
+// merchantExporter выгрузка RSS фида Google Merchant Center.
+type merchantExporter struct {
+	params config.GoogleMerchantParams
+	items  []merchant.Item
+}
+
+func (e *merchantExporter) Name() string {
+	return FormatGoogleMerchant
+}
+
+func (e *merchantExporter) FilePath() string {
+	return e.params.FilePath
+}
+
+func (e *merchantExporter) ServePath() string {
+	return e.params.URL
+}
+
+// Convert формирует товары фида Google. Товары без цены и изображений не выгружаются.
+func (e *merchantExporter) Convert(ctx context.Context, run *Run) error {
+	e.items = make([]merchant.Item, 0, len(run.Products))
+	renderer := description.NewRenderer()
+
+	for _, p := range run.Products {
+		p.Price = p.PriceByType(e.params.PriceType)
+		pictures := images.Prepare(p, config.Config.Images)
+
+		if p.Price <= 0 {
+			run.Report.Add(ReportItem(e.Name(), p, report.ReasonNoPrice))
+			continue
+		}
+
+		if len(pictures) == 0 {
+			reason := report.ReasonImagesFailed
+			if p.ImagesResponse.Meta.Size == 0 {
+				reason = report.ReasonNoImages
+			}
+
+			run.Report.Add(ReportItem(e.Name(), p, reason))
+			continue
+		}
+
+		text, err := renderer.Render(p)
+		if err != nil {
+			logger.Log.WithFields(logrus.Fields{
+				"error":     err,
+				"productId": p.ID,
+			}).Log(logrus.ErrorLevel, "Ошибка формирования описания товара для Google Merchant, используем описание по умолчанию")
+
+			text = renderer.RenderDefault(p)
+		}
+
+		item := merchant.Item{
+			ID:           p.ID,
+			Title:        p.Name,
+			Description:  description.ToText(text),
+			Link:         e.productLink(p),
+			ImageLink:    pictures[0].Url,
+			Price:        fmt.Sprintf("%d.00 RUB", p.Price),
+			Availability: merchant.OutOfStock,
+			Condition:    textutil.FirstNotEmpty(e.params.Condition, "new"),
+			GTIN:         p.Barcode(),
+			Brand:        textutil.FirstNotEmpty(p.Attributes[e.params.BrandAttribute], e.params.Brand),
+			MPN:          p.Article,
+			ProductType:  strings.ReplaceAll(p.Folder, "/", " > "),
+		}
+
+		if p.Stock > 0 {
+			item.Availability = merchant.InStock
+		}
+
+		for _, img := range pictures[1:] {
+			item.AdditionalImageLinks = append(item.AdditionalImageLinks, img.Url)
+		}
+
+		e.items = append(e.items, item)
+		run.Report.Add(ReportItem(e.Name(), p, report.ReasonExported))
+	}
+
+	sort.Slice(e.items, func(i, j int) bool {
+		return e.items[i].ID < e.items[j].ID
+	})
+
+	return nil
+}
+
+// productLink возвращает адрес страницы товара по шаблону из настроек.
+func (e *merchantExporter) productLink(p storage.Product) string {
+	return strings.NewReplacer("{id}", p.ID, "{article}", p.Article).Replace(e.params.ProductLink)
+}
+
+// Validate проверяет обязательные атрибуты Google и отмечает найденные проблемы в отчете.
+func (e *merchantExporter) Validate(ctx context.Context, run *Run) error {
+	valid, issues := merchant.Validate(e.items)
+
+	for _, issue := range issues {
+		if issue.Fixed {
+			run.Report.Warn(e.Name(), issue.ID, issue.String())
+		} else {
+			run.Report.Exclude(e.Name(), issue.ID, report.ReasonValidationErr, issue.String())
+		}
+	}
+
+	if len(issues) > 0 {
+		logger.Log.WithFields(logrus.Fields{
+			"issues": issues,
+		}).Logf(logrus.WarnLevel, "Проверка товаров Google Merchant: исключено %d из %d", len(e.items)-len(valid), len(e.items))
+	}
+
+	e.items = valid
+
+	for _, item := range valid {
+		run.AddListing(e.Name(), item.ID, Listing{
+			Title:    item.Title,
+			Price:    run.Products[item.ID].PriceByType(e.params.PriceType),
+			Category: item.ProductType,
+			Images:   append([]string{item.ImageLink}, item.AdditionalImageLinks...),
+		})
+	}
+
+	return nil
+}
+
+func (e *merchantExporter) Write(ctx context.Context, run *Run) error {
+	rss := merchant.NewRSS(e.params.Title, e.params.Link, e.params.Description, e.items)
+
+	if err := merchant.CreateFile(e.params.FilePath, rss); err != nil {
+		return err
+	}
+
+	logger.Log.Logf(logrus.InfoLevel, "В файл Google Merchant выгружено товаров: %d", len(e.items))
+
+	return nil
+}
@@ -0,0 +1,50 @@
+package export
+
+import (
+	"context"
+	"github.com/KirillKhitev/carat_export/internal/ozon"
+)
+
+// FormatOzon синхронизация карточек, цен и остатков с Ozon.
+const FormatOzon = "ozon"
+
+func init() {
+	Register(FormatOzon, func() []Exporter {
+		if !ozon.Enabled() {
+			return nil
+		}
+
+		return []Exporter{&ozonExporter{}}
+	})
+}
+
+// ozonExporter выгрузка товаров в Ozon через Seller API.
+type ozonExporter struct{}
+
+func (e *ozonExporter) Name() string {
+	return FormatOzon
+}
+
+func (e *ozonExporter) FilePath() string {
+	return ""
+}
+
+func (e *ozonExporter) ServePath() string {
+	return ""
+}
+
+func (e *ozonExporter) Convert(ctx context.Context, run *Run) error {
+	return nil
+}
+
+func (e *ozonExporter) Validate(ctx context.Context, run *Run) error {
+	return nil
+}
+
+// Write отправляет карточки, цены и остатки в Ozon.
+func (e *ozonExporter) Write(ctx context.Context, run *Run) error {
+	reasons, err := ozon.NewExporter().Sync(ctx, run.Products, run.StoresLoaded)
+	addReasons(run, e.Name(), reasons)
+
+	return err
+}
@@ -0,0 +1,50 @@
+package export
+
+import (
+	"context"
+	"github.com/KirillKhitev/carat_export/internal/vk"
+)
+
+// FormatVK синхронизация товаров и подборок VK Market.
+const FormatVK = "vk"
+
+func init() {
+	Register(FormatVK, func() []Exporter {
+		if !vk.Enabled() {
+			return nil
+		}
+
+		return []Exporter{&vkExporter{}}
+	})
+}
+
+// vkExporter выгрузка товаров в VK Market через API VK.
+type vkExporter struct{}
+
+func (e *vkExporter) Name() string {
+	return FormatVK
+}
+
+func (e *vkExporter) FilePath() string {
+	return ""
+}
+
+func (e *vkExporter) ServePath() string {
+	return ""
+}
+
+func (e *vkExporter) Convert(ctx context.Context, run *Run) error {
+	return nil
+}
+
+func (e *vkExporter) Validate(ctx context.Context, run *Run) error {
+	return nil
+}
+
+// Write создает, изменяет и удаляет товары и подборки VK Market.
+func (e *vkExporter) Write(ctx context.Context, run *Run) error {
+	reasons, err := vk.NewExporter().Sync(ctx, run.Products)
+	addReasons(run, e.Name(), reasons)
+
+	return err
+}
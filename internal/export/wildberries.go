@@ -0,0 +1,51 @@
+package export
+
+import (
+	"context"
+	"github.com/KirillKhitev/carat_export/internal/wildberries"
+)
+
+// FormatWildberries синхронизация цен и остатков с Wildberries.
+const FormatWildberries = "wildberries"
+
+func init() {
+	Register(FormatWildberries, func() []Exporter {
+		if !wildberries.Enabled() {
+			return nil
+		}
+
+		return []Exporter{&wildberriesExporter{}}
+	})
+}
+
+// wildberriesExporter выгрузка цен и остатков в Wildberries через API продавца.
+type wildberriesExporter struct{}
+
+func (e *wildberriesExporter) Name() string {
+	return FormatWildberries
+}
+
+func (e *wildberriesExporter) FilePath() string {
+	return ""
+}
+
+func (e *wildberriesExporter) ServePath() string {
+	return ""
+}
+
+func (e *wildberriesExporter) Convert(ctx context.Context, run *Run) error {
+	return nil
+}
+
+func (e *wildberriesExporter) Validate(ctx context.Context, run *Run) error {
+	return nil
+}
+
+// Write отправляет цены и остатки всех товаров МойСклад, чтобы закончившиеся товары получили
+// нулевой остаток. В отчет попадают только товары выгрузки.
+func (e *wildberriesExporter) Write(ctx context.Context, run *Run) error {
+	reasons, err := wildberries.NewExporter().Sync(ctx, run.AllProducts, run.StoresLoaded)
+	addReasons(run, e.Name(), reasons)
+
+	return err
+}
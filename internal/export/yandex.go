@@ -0,0 +1,136 @@
+package export
+
+import (
+	"context"
+	"github.com/KirillKhitev/carat_export/internal/config"
+	"github.com/KirillKhitev/carat_export/internal/description"
+	"github.com/KirillKhitev/carat_export/internal/images"
+	"github.com/KirillKhitev/carat_export/internal/logger"
+	"github.com/KirillKhitev/carat_export/internal/report"
+	"github.com/KirillKhitev/carat_export/internal/yml"
+	"github.com/sirupsen/logrus"
+	"sort"
+	"time"
+)
+
+// FormatYandexMarket формат YML фида Яндекс Маркета.
+const FormatYandexMarket = "yandex_market"
+
+func init() {
+	Register(FormatYandexMarket, func() []Exporter {
+		if !config.Config.YandexMarket.Enabled() {
+			return nil
+		}
+
+		return []Exporter{&yandexExporter{params: config.Config.YandexMarket}}
+	})
+}
+
+// yandexExporter выгрузка YML фида Яндекс Маркета.
+type yandexExporter struct {
+	params  config.YandexMarketParams
+	catalog yml.Catalog
+}
+
+func (e *yandexExporter) Name() string {
+	return FormatYandexMarket
+}
+
+func (e *yandexExporter) FilePath() string {
+	return e.params.FilePath
+}
+
+func (e *yandexExporter) ServePath() string {
+	return e.params.URL
+}
+
+// Convert формирует предложения YML и дерево категорий из групп товаров. Товары без цены
+// и изображений не выгружаются.
+func (e *yandexExporter) Convert(ctx context.Context, run *Run) error {
+	e.catalog = yml.NewCatalog(e.params.ShopName, e.params.Company, e.params.ShopURL, time.Now())
+	renderer := description.NewRenderer()
+	folders := make([]string, 0, len(run.Products))
+
+	for _, p := range run.Products {
+		p.Price = p.PriceByType(e.params.PriceType)
+		pictures := images.Prepare(p, config.Config.Images)
+
+		if p.Price <= 0 {
+			run.Report.Add(ReportItem(e.Name(), p, report.ReasonNoPrice))
+			continue
+		}
+
+		if len(pictures) == 0 {
+			reason := report.ReasonImagesFailed
+			if p.ImagesResponse.Meta.Size == 0 {
+				reason = report.ReasonNoImages
+			}
+
+			run.Report.Add(ReportItem(e.Name(), p, reason))
+			continue
+		}
+
+		text, err := renderer.Render(p)
+		if err != nil {
+			logger.Log.WithFields(logrus.Fields{
+				"error":     err,
+				"productId": p.ID,
+			}).Log(logrus.ErrorLevel, "Ошибка формирования описания товара для Яндекс Маркета, используем описание по умолчанию")
+
+			text = renderer.RenderDefault(p)
+		}
+
+		offer := yml.Offer{
+			ID:          p.ID,
+			Available:   p.Stock > 0,
+			Name:        p.Name,
+			Price:       p.Price,
+			CurrencyID:  yml.CurrencyRUB,
+			Description: yml.Description{Text: text},
+			VendorCode:  p.Article,
+			Barcode:     p.Barcode(),
+		}
+
+		if p.Folder != "" {
+			offer.CategoryID = yml.CategoryID(p.Folder)
+			folders = append(folders, p.Folder)
+		}
+
+		for _, img := range pictures {
+			offer.Pictures = append(offer.Pictures, img.Url)
+		}
+
+		for _, name := range e.params.Params {
+			if value := p.Attributes[name]; value != "" {
+				offer.Params = append(offer.Params, yml.Param{Name: name, Value: value})
+			}
+		}
+
+		e.catalog.Shop.Offers = append(e.catalog.Shop.Offers, offer)
+		run.Report.Add(ReportItem(e.Name(), p, report.ReasonExported))
+		run.AddListing(e.Name(), p.ID, Listing{Title: p.Name, Price: p.Price, Category: p.Folder, Images: offer.Pictures})
+	}
+
+	sort.Slice(e.catalog.Shop.Offers, func(i, j int) bool {
+		return e.catalog.Shop.Offers[i].ID < e.catalog.Shop.Offers[j].ID
+	})
+
+	e.catalog.Shop.Categories = yml.Categories(folders)
+
+	return nil
+}
+
+// Validate для YML не требуется: товары без обязательных полей отсеиваются при подготовке.
+func (e *yandexExporter) Validate(ctx context.Context, run *Run) error {
+	return nil
+}
+
+func (e *yandexExporter) Write(ctx context.Context, run *Run) error {
+	if err := yml.CreateFile(e.params.FilePath, e.catalog); err != nil {
+		return err
+	}
+
+	logger.Log.Logf(logrus.InfoLevel, "В файл Яндекс Маркета выгружено товаров: %d", len(e.catalog.Shop.Offers))
+
+	return nil
+}
@@ -0,0 +1,162 @@
+package history
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/KirillKhitev/carat_export/internal/avito"
+	"github.com/KirillKhitev/carat_export/internal/config"
+	"github.com/KirillKhitev/carat_export/internal/fileutil"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const ExportFileName = "avito_export.json"
+
+// exportFilePath путь до файла состояния выгрузки фида. Фид по умолчанию хранится в прежнем файле.
+func exportFilePath(feed string) string {
+	if feed == config.DefaultFeedName {
+		return filepath.Join(config.Config.HistoryDir, ExportFileName)
+	}
+
+	return filepath.Join(config.Config.HistoryDir, "avito_export_"+feed+".json")
+}
+
+// ExportedProduct объявление, которое было в выгрузке Avito.
+type ExportedProduct struct {
+	Product         avito.Product `json:"product"`
+	FirstExportedAt time.Time     `json:"first_exported_at"`
+	LastExportedAt  time.Time     `json:"last_exported_at"`
+	RemovedAt       time.Time     `json:"removed_at,omitempty"`
+}
+
+// Removed показывает, что товар пропал из выгрузки и объявление закрывается.
+func (p ExportedProduct) Removed() bool {
+	return !p.RemovedAt.IsZero()
+}
+
+// Store хранит состояние предыдущей выгрузки между запусками.
+type Store struct {
+	m        *sync.RWMutex
+	path     string
+	Products map[string]ExportedProduct `json:"products"`
+}
+
+// Load читает состояние предыдущей выгрузки фида с диска. Если файла нет, возвращает пустое состояние.
+func Load(feed string) (*Store, error) {
+	s := &Store{
+		m:        &sync.RWMutex{},
+		path:     exportFilePath(feed),
+		Products: make(map[string]ExportedProduct),
+	}
+
+	if err := readJSON(s.path, s); err != nil {
+		return nil, err
+	}
+
+	if s.Products == nil {
+		s.Products = make(map[string]ExportedProduct)
+	}
+
+	return s, nil
+}
+
+// Save сохраняет состояние выгрузки на диск.
+func (s *Store) Save() error {
+	s.m.RLock()
+	defer s.m.RUnlock()
+
+	return writeJSON(s.path, s)
+}
+
+// FirstExportedAt возвращает время первой выгрузки товара. Для неизвестного товара
+// возвращает false.
+func (s *Store) FirstExportedAt(id string) (time.Time, bool) {
+	if s == nil {
+		return time.Time{}, false
+	}
+
+	s.m.RLock()
+	defer s.m.RUnlock()
+
+	p, ok := s.Products[id]
+
+	return p.FirstExportedAt, ok
+}
+
+// Update запоминает текущую выгрузку и возвращает объявления, которые нужно оставить в файле
+// закрытыми: товары, пропавшие из выгрузки не более keep назад.
+func (s *Store) Update(products []avito.Product, now time.Time, keep time.Duration) []avito.Product {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	current := make(map[string]struct{}, len(products))
+
+	for _, p := range products {
+		current[p.ID] = struct{}{}
+
+		exported, ok := s.Products[p.ID]
+		if !ok {
+			exported.FirstExportedAt = now
+		}
+
+		exported.Product = p
+		exported.LastExportedAt = now
+		exported.RemovedAt = time.Time{}
+
+		s.Products[p.ID] = exported
+	}
+
+	removed := make([]avito.Product, 0)
+
+	for id, exported := range s.Products {
+		if _, ok := current[id]; ok {
+			continue
+		}
+
+		if !exported.Removed() {
+			exported.RemovedAt = now
+		}
+
+		if now.Sub(exported.RemovedAt) > keep {
+			delete(s.Products, id)
+			continue
+		}
+
+		exported.Product.DateEnd = avito.FormatDate(exported.RemovedAt)
+		s.Products[id] = exported
+
+		removed = append(removed, exported.Product)
+	}
+
+	return removed
+}
+
+// readJSON читает JSON файл в v. Отсутствие файла не является ошибкой.
+func readJSON(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+// writeJSON атомарно сохраняет v в JSON файл.
+func writeJSON(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "   ")
+	if err != nil {
+		return err
+	}
+
+	return fileutil.WriteAtomic(path, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
@@ -0,0 +1,120 @@
+package history
+
+import (
+	"github.com/KirillKhitev/carat_export/internal/config"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// LedgerEntry запись об обработанном объекте.
+type LedgerEntry struct {
+	Value string    `json:"value,omitempty"`
+	At    time.Time `json:"at"`
+}
+
+// Ledger журнал обработанных объектов (заказов, чатов, публикаций), чтобы не обрабатывать их повторно.
+// Хранится в JSON файле в папке истории и может редактироваться вручную.
+type Ledger struct {
+	m       *sync.RWMutex
+	path    string
+	Entries map[string]LedgerEntry `json:"entries"`
+	// SeededAt время первого заполнения журнала существующими объектами без их обработки.
+	SeededAt time.Time `json:"seeded_at,omitempty"`
+}
+
+// LoadLedger читает журнал с именем name из папки истории.
+func LoadLedger(name string) (*Ledger, error) {
+	l := &Ledger{
+		m:       &sync.RWMutex{},
+		path:    filepath.Join(config.Config.HistoryDir, name+".json"),
+		Entries: make(map[string]LedgerEntry),
+	}
+
+	if err := readJSON(l.path, l); err != nil {
+		return nil, err
+	}
+
+	if l.Entries == nil {
+		l.Entries = make(map[string]LedgerEntry)
+	}
+
+	return l, nil
+}
+
+// Has проверяет, есть ли объект в журнале.
+func (l *Ledger) Has(id string) bool {
+	l.m.RLock()
+	defer l.m.RUnlock()
+
+	_, ok := l.Entries[id]
+
+	return ok
+}
+
+// Get возвращает запись журнала по объекту.
+func (l *Ledger) Get(id string) (LedgerEntry, bool) {
+	l.m.RLock()
+	defer l.m.RUnlock()
+
+	entry, ok := l.Entries[id]
+
+	return entry, ok
+}
+
+// Add отмечает объект как обработанный.
+func (l *Ledger) Add(id string, value string) {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	l.Entries[id] = LedgerEntry{Value: value, At: time.Now()}
+}
+
+// WithValue возвращает объекты журнала с указанным значением.
+func (l *Ledger) WithValue(value string) []string {
+	l.m.RLock()
+	defer l.m.RUnlock()
+
+	result := make([]string, 0)
+
+	for id, entry := range l.Entries {
+		if entry.Value == value {
+			result = append(result, id)
+		}
+	}
+
+	return result
+}
+
+// Seeded показывает, заполнялся ли журнал при первом запуске. Журналы, сохраненные до появления
+// отметки, считаются заполненными, если в них есть записи.
+func (l *Ledger) Seeded() bool {
+	l.m.RLock()
+	defer l.m.RUnlock()
+
+	return !l.SeededAt.IsZero() || len(l.Entries) > 0
+}
+
+// MarkSeeded отмечает, что журнал заполнен при первом запуске, даже если объектов не было.
+func (l *Ledger) MarkSeeded() {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	l.SeededAt = time.Now()
+}
+
+// Save сохраняет журнал на диск.
+func (l *Ledger) Save() error {
+	l.m.RLock()
+	defer l.m.RUnlock()
+
+	return writeJSON(l.path, l)
+}
+
+// Delete удаляет объект из журнала, чтобы он был обработан заново.
+func (l *Ledger) Delete(id string) {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	delete(l.Entries, id)
+}
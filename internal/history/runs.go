@@ -0,0 +1,123 @@
+package history
+
+import (
+	"github.com/KirillKhitev/carat_export/internal/config"
+	"github.com/KirillKhitev/carat_export/internal/report"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	RunsFileName = "runs.json"
+	// maxRuns сколько последних запусков хранить в истории.
+	maxRuns = 100
+)
+
+// Run итоги одного запуска выгрузки.
+type Run struct {
+	StartedAt  time.Time          `json:"started_at"`
+	FinishedAt time.Time          `json:"finished_at"`
+	Exported   int                `json:"exported"`
+	Excluded   int                `json:"excluded"`
+	Feeds      map[string]FeedRun `json:"feeds,omitempty"`
+}
+
+// FeedRun результаты обработки фида в Avito по отчету автозагрузки.
+type FeedRun struct {
+	AutoloadReportId int                           `json:"autoload_report_id,omitempty"`
+	Ads              map[string]report.AvitoStatus `json:"ads,omitempty"`
+}
+
+// SetFeed сохраняет результаты обработки фида.
+func (r *Run) SetFeed(feed string, feedRun FeedRun) {
+	if r.Feeds == nil {
+		r.Feeds = make(map[string]FeedRun)
+	}
+
+	r.Feeds[feed] = feedRun
+}
+
+// Runs история запусков выгрузки.
+type Runs struct {
+	m    *sync.RWMutex
+	path string
+	Runs []Run `json:"runs"`
+}
+
+// LoadRuns читает историю запусков с диска.
+func LoadRuns() (*Runs, error) {
+	r := &Runs{
+		m:    &sync.RWMutex{},
+		path: filepath.Join(config.Config.HistoryDir, RunsFileName),
+		Runs: make([]Run, 0),
+	}
+
+	if err := readJSON(r.path, r); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Add добавляет запуск в историю, удаляя самые старые записи сверх лимита.
+func (r *Runs) Add(run Run) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	r.Runs = append(r.Runs, run)
+
+	if len(r.Runs) > maxRuns {
+		r.Runs = r.Runs[len(r.Runs)-maxRuns:]
+	}
+}
+
+// LastAds возвращает статусы объявлений фида из последнего запуска, в котором был получен отчет Avito.
+func (r *Runs) LastAds(feed string) map[string]report.AvitoStatus {
+	r.m.RLock()
+	defer r.m.RUnlock()
+
+	for i := len(r.Runs) - 1; i >= 0; i-- {
+		if feedRun, ok := r.Runs[i].Feeds[feed]; ok && feedRun.Ads != nil {
+			return feedRun.Ads
+		}
+	}
+
+	return nil
+}
+
+// Save сохраняет историю запусков на диск.
+func (r *Runs) Save() error {
+	r.m.RLock()
+	defer r.m.RUnlock()
+
+	return writeJSON(r.path, r)
+}
+
+// AdProductIds сопоставляет номера объявлений Avito всех фидов с Id объявлений в выгрузке, которые
+// совпадают с ID товаров МойСклад. Данные берутся из отчета автозагрузки и истории выгрузки.
+func AdProductIds() map[string]string {
+	result := make(map[string]string)
+
+	runs, err := LoadRuns()
+
+	for _, feed := range config.Config.AvitoFeeds() {
+		if err == nil {
+			for adId, status := range runs.LastAds(feed.Name) {
+				if status.AvitoId != "" {
+					result[status.AvitoId] = adId
+				}
+			}
+		}
+
+		if exportHistory, err := Load(feed.Name); err == nil {
+			for id, p := range exportHistory.Products {
+				if p.Product.AvitoId != "" {
+					result[p.Product.AvitoId] = id
+				}
+			}
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,103 @@
+package images
+
+import (
+	"github.com/KirillKhitev/carat_export/internal/config"
+	"github.com/KirillKhitev/carat_export/internal/storage"
+	"github.com/KirillKhitev/carat_export/internal/textutil"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const OrderFilename = "filename"
+
+// patterns разобранные шаблоны имен файлов по тексту шаблона.
+var patterns = struct {
+	sync.Mutex
+	compiled map[string]*regexp.Regexp
+}{compiled: make(map[string]*regexp.Regexp)}
+
+// Prepare возвращает изображения товара для объявления: упорядочивает их, ставит главное
+// изображение первым и ограничивает количество. Товару без фотографий в МойСклад ставится
+// брендированная обложка, а если ее нет - заглушка. Если фотографии есть, но не скачались,
+// возвращается пустой список.
+func Prepare(p storage.Product, params config.ImagesParams) []storage.Image {
+	result := Order(p, params)
+
+	if p.ImagesResponse.Meta.Size == 0 {
+		if image := textutil.FirstNotEmpty(params.Cover, params.Placeholder); image != "" {
+			result = []storage.Image{{Url: image}}
+		}
+	}
+
+	if params.Max > 0 && len(result) > params.Max {
+		result = result[:params.Max]
+	}
+
+	return result
+}
+
+// Order упорядочивает изображения товара: по имени файла или в порядке МойСклад, затем по
+// шаблонам имен, а изображение из доп. поля главного изображения ставится первым.
+func Order(p storage.Product, params config.ImagesParams) []storage.Image {
+	result := slices.Clone(p.Images)
+
+	if params.Order == OrderFilename {
+		sort.SliceStable(result, func(i, j int) bool {
+			return strings.ToLower(result[i].Filename) < strings.ToLower(result[j].Filename)
+		})
+	}
+
+	if len(params.Patterns) > 0 {
+		compiled := compilePatterns(params.Patterns)
+
+		rank := func(img storage.Image) int {
+			for i, re := range compiled {
+				if re.MatchString(img.Filename) {
+					return i
+				}
+			}
+
+			return len(compiled)
+		}
+
+		sort.SliceStable(result, func(i, j int) bool {
+			return rank(result[i]) < rank(result[j])
+		})
+	}
+
+	if params.MainAttribute != "" {
+		main := strings.TrimSpace(p.Attributes[params.MainAttribute])
+
+		if i := slices.IndexFunc(result, func(img storage.Image) bool { return main != "" && img.Filename == main }); i > 0 {
+			img := result[i]
+			result = append(result[:i], result[i+1:]...)
+			result = append([]storage.Image{img}, result...)
+		}
+	}
+
+	return result
+}
+
+// compilePatterns возвращает разобранные шаблоны имен файлов, разбирая каждый шаблон один раз.
+// Шаблоны проверяются при чтении настроек.
+func compilePatterns(texts []string) []*regexp.Regexp {
+	patterns.Lock()
+	defer patterns.Unlock()
+
+	result := make([]*regexp.Regexp, 0, len(texts))
+
+	for _, text := range texts {
+		re, ok := patterns.compiled[text]
+		if !ok {
+			re = regexp.MustCompile(text)
+			patterns.compiled[text] = re
+		}
+
+		result = append(result, re)
+	}
+
+	return result
+}
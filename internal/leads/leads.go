@@ -0,0 +1,254 @@
+package leads
+
+import (
+	"context"
+	"fmt"
+	"github.com/KirillKhitev/carat_export/internal/avitoapi"
+	"github.com/KirillKhitev/carat_export/internal/config"
+	"github.com/KirillKhitev/carat_export/internal/history"
+	"github.com/KirillKhitev/carat_export/internal/logger"
+	"github.com/KirillKhitev/carat_export/internal/storage"
+	"github.com/KirillKhitev/carat_export/internal/timeutil"
+	"github.com/sirupsen/logrus"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LedgerName журнал обработанных чатов: ID чата Avito -> ID задачи МойСклад.
+const LedgerName = "avito_chats"
+
+// seedValue значение записей журнала, добавленных без обработки при первом запуске.
+const seedValue = "seed"
+
+// productAppURL ссылка на карточку товара в веб-интерфейсе МойСклад.
+const productAppURL = "https://online.moysklad.ru/app/#good/edit?id="
+
+// defaultDueHours срок задачи, если он не задан в настройках.
+const defaultDueHours = 24
+
+// Poller периодически забирает новые чаты мессенджера Avito, заводит по ним контрагентов
+// и ставит задачи ответственному сотруднику в МойСклад.
+type Poller struct {
+	storage  *storage.MoySklad
+	avitoApi *avitoapi.Client
+	stopChan chan struct{}
+	wg       *sync.WaitGroup
+}
+
+func NewPoller() *Poller {
+	return &Poller{
+		storage:  storage.NewMoySklad(),
+		avitoApi: avitoapi.NewClient(),
+		stopChan: make(chan struct{}),
+		wg:       &sync.WaitGroup{},
+	}
+}
+
+// Enabled показывает, настроена ли обработка чатов.
+func Enabled() bool {
+	return avitoapi.Enabled() && config.Config.Leads.Enabled()
+}
+
+// Start запускает периодическую обработку чатов.
+func (p *Poller) Start(ctx context.Context) {
+	if !Enabled() {
+		return
+	}
+
+	p.wg.Add(1)
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(time.Second * time.Duration(config.Config.Leads.Interval))
+	defer ticker.Stop()
+
+	for {
+		if err := p.Poll(ctx); err != nil {
+			logger.Log.WithFields(logrus.Fields{
+				"error": err,
+			}).Log(logrus.ErrorLevel, "Ошибка при обработке чатов Avito")
+		}
+
+		select {
+		case <-p.stopChan:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *Poller) Close() error {
+	close(p.stopChan)
+	p.wg.Wait()
+
+	logger.Log.Logln(logrus.InfoLevel, "Обработка чатов Avito остановлена")
+
+	return nil
+}
+
+// Poll обрабатывает чаты, которых еще нет в журнале. Каждый чат обрабатывается один раз.
+// При первом запуске существующие чаты заносятся в журнал без обработки, чтобы не ставить
+// задачи по всей истории переписки. Чаты загружаются до первой страницы, на которой
+// все чаты уже есть в журнале.
+func (p *Poller) Poll(ctx context.Context) error {
+	account, err := p.avitoApi.Self(ctx)
+	if err != nil {
+		return err
+	}
+
+	ledger, err := history.LoadLedger(LedgerName)
+	if err != nil {
+		return err
+	}
+
+	seed := !ledger.Seeded()
+
+	var stop func(page []avitoapi.Chat) bool
+	if !seed {
+		stop = func(page []avitoapi.Chat) bool {
+			for _, chat := range page {
+				if !ledger.Has(chat.Id) {
+					return false
+				}
+			}
+
+			return true
+		}
+	}
+
+	chats, err := p.avitoApi.Chats(ctx, account.Id, stop)
+	if err != nil {
+		return err
+	}
+
+	if seed {
+		for _, chat := range chats {
+			ledger.Add(chat.Id, seedValue)
+		}
+
+		ledger.MarkSeeded()
+
+		logger.Log.Logf(logrus.InfoLevel, "Журнал чатов Avito заполнен существующими чатами: %d", len(chats))
+
+		return ledger.Save()
+	}
+
+	productIds := history.AdProductIds()
+
+	for _, chat := range chats {
+		if ledger.Has(chat.Id) {
+			continue
+		}
+
+		taskId, err := p.processChat(ctx, chat, account.Id, productIds)
+		if err != nil {
+			logger.Log.WithFields(logrus.Fields{
+				"error":  err,
+				"chatId": chat.Id,
+			}).Log(logrus.ErrorLevel, "Не удалось обработать чат Avito")
+
+			continue
+		}
+
+		ledger.Add(chat.Id, taskId)
+
+		if err := ledger.Save(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// processChat находит или создает контрагента покупателя и ставит задачу по чату.
+func (p *Poller) processChat(ctx context.Context, chat avitoapi.Chat, selfId int64, productIds map[string]string) (string, error) {
+	buyer, ok := chatBuyer(chat, selfId)
+	if !ok {
+		return "", fmt.Errorf("в чате нет покупателя")
+	}
+
+	counterparty, err := p.counterparty(ctx, buyer)
+	if err != nil {
+		return "", err
+	}
+
+	agent := storage.NewRef("counterparty", counterparty.Id)
+
+	dueHours := config.Config.Leads.DueHours
+	if dueHours <= 0 {
+		dueHours = defaultDueHours
+	}
+
+	task, err := p.storage.CreateTask(ctx, storage.Task{
+		Description: taskDescription(chat, buyer, productIds),
+		Assignee:    storage.NewRef("employee", config.Config.Leads.Employee),
+		Agent:       &agent,
+		DueToDate:   time.Now().In(timeutil.Moscow).Add(time.Hour * time.Duration(dueHours)).Format(storage.DateTimeFormat),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	logger.Log.WithFields(logrus.Fields{
+		"chatId": chat.Id,
+		"taskId": task.Id,
+	}).Logf(logrus.InfoLevel, "Создали задачу по чату Avito с покупателем %s", buyer.Name)
+
+	return task.Id, nil
+}
+
+// counterparty ищет контрагента покупателя по внешнему коду или создает нового.
+func (p *Poller) counterparty(ctx context.Context, buyer avitoapi.ChatUser) (storage.Counterparty, error) {
+	externalCode := "avito-user-" + strconv.FormatInt(buyer.Id, 10)
+
+	existing, err := p.storage.FindCounterparty(ctx, externalCode)
+	if err != nil {
+		return storage.Counterparty{}, err
+	}
+
+	if existing != nil {
+		return *existing, nil
+	}
+
+	name := buyer.Name
+	if name == "" {
+		name = "Покупатель Avito " + strconv.FormatInt(buyer.Id, 10)
+	}
+
+	return p.storage.CreateCounterparty(ctx, storage.Counterparty{
+		Name:         name,
+		ExternalCode: externalCode,
+		CompanyType:  "individual",
+		Description:  "Создан по чату мессенджера Avito",
+	})
+}
+
+// chatBuyer возвращает собеседника в чате, то есть участника, который не является нами.
+func chatBuyer(chat avitoapi.Chat, selfId int64) (avitoapi.ChatUser, bool) {
+	for _, u := range chat.Users {
+		if u.Id != selfId {
+			return u, true
+		}
+	}
+
+	return avitoapi.ChatUser{}, false
+}
+
+// taskDescription формирует текст задачи с объявлением и товаром МойСклад.
+func taskDescription(chat avitoapi.Chat, buyer avitoapi.ChatUser, productIds map[string]string) string {
+	lines := []string{
+		fmt.Sprintf("Новое обращение в мессенджере Avito от %s", buyer.Name),
+	}
+
+	if chat.Context.Type == "item" {
+		item := chat.Context.Value
+		lines = append(lines, fmt.Sprintf("Объявление: %s %s %s", item.Title, item.PriceString, item.Url))
+
+		if productId, ok := productIds[strconv.FormatInt(item.Id, 10)]; ok {
+			lines = append(lines, "Товар: "+productAppURL+productId)
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,145 @@
+package leads
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/KirillKhitev/carat_export/internal/avitoapi"
+	"github.com/KirillKhitev/carat_export/internal/config"
+	"github.com/KirillKhitev/carat_export/internal/history"
+	"github.com/KirillKhitev/carat_export/internal/storage"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// stub заглушка API Avito и МойСклад: чаты аккаунта 1 и созданные задачи.
+type stub struct {
+	m     sync.Mutex
+	chats []avitoapi.Chat
+	tasks []storage.Task
+}
+
+func (s *stub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.URL.Path {
+	case "/token":
+		fmt.Fprint(w, `{"access_token":"token","expires_in":86400}`)
+	case "/core/v1/accounts/self":
+		fmt.Fprint(w, `{"id":1,"name":"Магазин"}`)
+	case "/messenger/v2/accounts/1/chats":
+		json.NewEncoder(w).Encode(map[string]any{"chats": s.chats})
+	case "/api/entity/counterparty":
+		if r.Method == http.MethodGet {
+			fmt.Fprint(w, `{"rows":[]}`)
+			return
+		}
+
+		var counterparty storage.Counterparty
+		json.NewDecoder(r.Body).Decode(&counterparty)
+
+		counterparty.Id = "counterparty-" + counterparty.ExternalCode
+		json.NewEncoder(w).Encode(counterparty)
+	case "/api/entity/task":
+		var task storage.Task
+		json.NewDecoder(r.Body).Decode(&task)
+
+		task.Id = fmt.Sprintf("task-%d", len(s.tasks)+1)
+		s.tasks = append(s.tasks, task)
+
+		json.NewEncoder(w).Encode(task)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newStub(t *testing.T) *stub {
+	t.Helper()
+
+	s := &stub{}
+
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+
+	old := config.Config
+	t.Cleanup(func() { config.Config = old })
+
+	config.Config.HistoryDir = t.TempDir()
+	config.Config.AvitoApiUrl = srv.URL
+	config.Config.AvitoClientId = "id"
+	config.Config.AvitoClientSecret = "secret"
+	config.Config.MoySkladUrl = srv.URL + "/api/"
+	config.Config.Feeds = nil
+	config.Config.Leads = config.LeadsParams{Interval: 60, Employee: "employee"}
+
+	return s
+}
+
+func chat(id string, buyerId int64) avitoapi.Chat {
+	return avitoapi.Chat{
+		Id:    id,
+		Users: []avitoapi.ChatUser{{Id: 1, Name: "Магазин"}, {Id: buyerId, Name: "Покупатель"}},
+	}
+}
+
+func poll(t *testing.T) {
+	t.Helper()
+
+	if err := NewPoller().Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFirstPollSeedsLedger(t *testing.T) {
+	s := newStub(t)
+	s.chats = []avitoapi.Chat{chat("old", 2)}
+
+	poll(t)
+
+	if len(s.tasks) != 0 {
+		t.Fatalf("tasks = %+v, want none for historical chats", s.tasks)
+	}
+
+	s.chats = append([]avitoapi.Chat{chat("new", 3)}, s.chats...)
+
+	poll(t)
+
+	if len(s.tasks) != 1 || s.tasks[0].Agent == nil {
+		t.Fatalf("tasks = %+v, want one task for new chat", s.tasks)
+	}
+
+	ledger, err := history.LoadLedger(LedgerName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if entry, _ := ledger.Get("new"); entry.Value != "task-1" {
+		t.Fatalf("ledger[new] = %q, want task-1", entry.Value)
+	}
+}
+
+func TestNewChatAfterEmptyAccountIsProcessed(t *testing.T) {
+	s := newStub(t)
+
+	poll(t)
+	poll(t)
+
+	s.chats = []avitoapi.Chat{chat("first", 2)}
+
+	poll(t)
+
+	if len(s.tasks) != 1 {
+		t.Fatalf("tasks = %+v, want task for the first chat of empty account", s.tasks)
+	}
+
+	poll(t)
+
+	if len(s.tasks) != 1 {
+		t.Fatalf("tasks = %+v, want chat processed once", s.tasks)
+	}
+}
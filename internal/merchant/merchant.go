@@ -0,0 +1,85 @@
+package merchant
+
+import (
+	"encoding/xml"
+	"github.com/KirillKhitev/carat_export/internal/fileutil"
+	"github.com/KirillKhitev/carat_export/internal/logger"
+	"github.com/sirupsen/logrus"
+	"io"
+)
+
+// Namespace пространство имен атрибутов товаров Google Merchant Center.
+const Namespace = "http://base.google.com/ns/1.0"
+
+// Значения g:availability.
+const (
+	InStock    = "in_stock"
+	OutOfStock = "out_of_stock"
+)
+
+// RSS корневой элемент фида Google Merchant Center в формате RSS 2.0.
+type RSS struct {
+	XMLName xml.Name `xml:"rss"`
+	Version string   `xml:"version,attr"`
+	G       string   `xml:"xmlns:g,attr"`
+	Channel Channel  `xml:"channel"`
+}
+
+type Channel struct {
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	Description string `xml:"description"`
+	Items       []Item `xml:"item"`
+}
+
+// Item товар фида.
+type Item struct {
+	ID                   string   `xml:"g:id"`
+	Title                string   `xml:"g:title"`
+	Description          string   `xml:"g:description"`
+	Link                 string   `xml:"g:link"`
+	ImageLink            string   `xml:"g:image_link"`
+	AdditionalImageLinks []string `xml:"g:additional_image_link"`
+	Price                string   `xml:"g:price"`
+	Availability         string   `xml:"g:availability"`
+	Condition            string   `xml:"g:condition,omitempty"`
+	GTIN                 string   `xml:"g:gtin,omitempty"`
+	Brand                string   `xml:"g:brand,omitempty"`
+	MPN                  string   `xml:"g:mpn,omitempty"`
+	IdentifierExists     string   `xml:"g:identifier_exists,omitempty"`
+	ProductType          string   `xml:"g:product_type,omitempty"`
+}
+
+// NewRSS создает фид с описанием магазина.
+func NewRSS(title, link, description string, items []Item) RSS {
+	return RSS{
+		Version: "2.0",
+		G:       Namespace,
+		Channel: Channel{
+			Title:       title,
+			Link:        link,
+			Description: description,
+			Items:       items,
+		},
+	}
+}
+
+// CreateFile атомарно сохраняет фид в файл.
+func CreateFile(path string, rss RSS) error {
+	logger.Log.Logf(logrus.InfoLevel, "Сохраняем товары в файл Google Merchant %s", path)
+
+	return fileutil.WriteAtomic(path, func(w io.Writer) error {
+		if _, err := io.WriteString(w, xml.Header); err != nil {
+			return err
+		}
+
+		enc := xml.NewEncoder(w)
+		enc.Indent("", "   ")
+
+		if err := enc.Encode(rss); err != nil {
+			return err
+		}
+
+		return enc.Flush()
+	})
+}
@@ -0,0 +1,144 @@
+package merchant
+
+import (
+	"fmt"
+	"github.com/KirillKhitev/carat_export/internal/textutil"
+	"net/url"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// Ограничения Google Merchant Center.
+const (
+	MaxTitleLength       = 150
+	MaxDescriptionLength = 5000
+	MaxAdditionalImages  = 10
+)
+
+// Issue проблема, найденная при проверке товара.
+type Issue struct {
+	ID      string
+	Field   string
+	Message string
+	// Fixed - товар исправлен и остался в фиде, иначе он исключен.
+	Fixed bool
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("%s: %s", i.Field, i.Message)
+}
+
+// Validate проверяет товары по обязательным атрибутам Google. Исправимые ошибки исправляются,
+// товары с неисправимыми ошибками исключаются из результата.
+func Validate(items []Item) ([]Item, []Issue) {
+	result := make([]Item, 0, len(items))
+	issues := make([]Issue, 0)
+
+	for _, item := range items {
+		itemIssues := validateItem(&item)
+		issues = append(issues, itemIssues...)
+
+		if slices.ContainsFunc(itemIssues, func(i Issue) bool { return !i.Fixed }) {
+			continue
+		}
+
+		result = append(result, item)
+	}
+
+	return result, issues
+}
+
+// validateItem проверяет один товар, исправляя то, что можно исправить.
+func validateItem(item *Item) []Issue {
+	issues := make([]Issue, 0)
+
+	fail := func(field, message string) {
+		issues = append(issues, Issue{ID: item.ID, Field: field, Message: message})
+	}
+
+	fix := func(field, message string) {
+		issues = append(issues, Issue{ID: item.ID, Field: field, Message: message, Fixed: true})
+	}
+
+	if item.ID == "" {
+		fail("id", "пустой идентификатор товара")
+	}
+
+	if strings.TrimSpace(item.Title) == "" {
+		fail("title", "пустое название")
+	} else if utf8.RuneCountInString(item.Title) > MaxTitleLength {
+		item.Title = textutil.Truncate(item.Title, MaxTitleLength)
+		fix("title", fmt.Sprintf("название обрезано до %d символов", MaxTitleLength))
+	}
+
+	if strings.TrimSpace(item.Description) == "" {
+		fail("description", "пустое описание")
+	} else if utf8.RuneCountInString(item.Description) > MaxDescriptionLength {
+		item.Description = textutil.Truncate(item.Description, MaxDescriptionLength)
+		fix("description", fmt.Sprintf("описание обрезано до %d символов", MaxDescriptionLength))
+	}
+
+	if !absoluteURL(item.Link) {
+		fail("link", "нет абсолютной ссылки на страницу товара")
+	}
+
+	if !absoluteURL(item.ImageLink) {
+		fail("image_link", "нет абсолютной ссылки на изображение")
+	}
+
+	if len(item.AdditionalImageLinks) > MaxAdditionalImages {
+		item.AdditionalImageLinks = item.AdditionalImageLinks[:MaxAdditionalImages]
+		fix("additional_image_link", fmt.Sprintf("оставлены первые %d дополнительных изображений", MaxAdditionalImages))
+	}
+
+	if item.Price == "" {
+		fail("price", "не указана цена")
+	}
+
+	if item.Availability != InStock && item.Availability != OutOfStock {
+		fail("availability", "неверное значение наличия")
+	}
+
+	if item.GTIN != "" && !validGTIN(item.GTIN) {
+		item.GTIN = ""
+		fix("gtin", "удален штрихкод в неверном формате")
+	}
+
+	if item.GTIN == "" && (item.Brand == "" || item.MPN == "") {
+		item.IdentifierExists = "no"
+	}
+
+	return issues
+}
+
+// absoluteURL проверяет, что ссылка абсолютная и ведет на http или https.
+func absoluteURL(rawUrl string) bool {
+	u, err := url.Parse(rawUrl)
+
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// validGTIN проверяет длину и контрольную цифру GTIN (EAN-8, UPC, EAN-13, GTIN-14).
+func validGTIN(gtin string) bool {
+	if !slices.Contains([]int{8, 12, 13, 14}, len(gtin)) {
+		return false
+	}
+
+	sum := 0
+
+	for i := len(gtin) - 1; i >= 0; i-- {
+		digit := int(gtin[i] - '0')
+		if digit < 0 || digit > 9 {
+			return false
+		}
+
+		if (len(gtin)-1-i)%2 == 1 {
+			digit *= 3
+		}
+
+		sum += digit
+	}
+
+	return sum%10 == 0
+}
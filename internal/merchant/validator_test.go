@@ -0,0 +1,33 @@
+package merchant
+
+import "testing"
+
+func TestValidGTIN(t *testing.T) {
+	tests := []struct {
+		gtin string
+		want bool
+	}{
+		{"4006381333931", true},
+		{"4006381333932", false},
+		{"96385074", true},
+		{"96385075", false},
+		{"036000291452", true},
+		{"036000291453", false},
+		{"10012345678902", true},
+		{"10012345678903", false},
+		{"0000000000000", true},
+		{"400638133393", false},
+		{"40063813339311", false},
+		{"400638133393A", false},
+		{"4006381 33931", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.gtin, func(t *testing.T) {
+			if got := validGTIN(tt.gtin); got != tt.want {
+				t.Errorf("validGTIN(%q) = %v, want %v", tt.gtin, got, tt.want)
+			}
+		})
+	}
+}
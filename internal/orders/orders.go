@@ -0,0 +1,194 @@
+package orders
+
+import (
+	"context"
+	"fmt"
+	"github.com/KirillKhitev/carat_export/internal/avitoapi"
+	"github.com/KirillKhitev/carat_export/internal/config"
+	"github.com/KirillKhitev/carat_export/internal/history"
+	"github.com/KirillKhitev/carat_export/internal/logger"
+	"github.com/KirillKhitev/carat_export/internal/storage"
+	"github.com/KirillKhitev/carat_export/internal/textutil"
+	"github.com/sirupsen/logrus"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LedgerName журнал загруженных заказов: номер заказа Avito -> ID заказа покупателя МойСклад.
+const LedgerName = "avito_orders"
+
+// Importer периодически загружает заказы Авито Доставки в МойСклад как заказы покупателей
+// с резервированием товара.
+type Importer struct {
+	storage  *storage.MoySklad
+	avitoApi *avitoapi.Client
+	stopChan chan struct{}
+	wg       *sync.WaitGroup
+}
+
+func NewImporter() *Importer {
+	return &Importer{
+		storage:  storage.NewMoySklad(),
+		avitoApi: avitoapi.NewClient(),
+		stopChan: make(chan struct{}),
+		wg:       &sync.WaitGroup{},
+	}
+}
+
+// Enabled показывает, настроена ли загрузка заказов.
+func Enabled() bool {
+	return avitoapi.Enabled() && config.Config.Orders.Enabled()
+}
+
+// Start запускает периодическую загрузку заказов.
+func (i *Importer) Start(ctx context.Context) {
+	if !Enabled() {
+		return
+	}
+
+	i.wg.Add(1)
+	defer i.wg.Done()
+
+	ticker := time.NewTicker(time.Second * time.Duration(config.Config.Orders.Interval))
+	defer ticker.Stop()
+
+	for {
+		if err := i.Import(ctx); err != nil {
+			logger.Log.WithFields(logrus.Fields{
+				"error": err,
+			}).Log(logrus.ErrorLevel, "Ошибка при загрузке заказов Avito")
+		}
+
+		select {
+		case <-i.stopChan:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (i *Importer) Close() error {
+	close(i.stopChan)
+	i.wg.Wait()
+
+	logger.Log.Logln(logrus.InfoLevel, "Загрузка заказов Avito остановлена")
+
+	return nil
+}
+
+// Import загружает новые заказы. Повторная загрузка заказа исключена журналом и поиском
+// заказа покупателя по внешнему коду.
+func (i *Importer) Import(ctx context.Context) error {
+	orders, err := i.avitoApi.Orders(ctx, config.Config.Orders.Statuses)
+	if err != nil {
+		return err
+	}
+
+	ledger, err := history.LoadLedger(LedgerName)
+	if err != nil {
+		return err
+	}
+
+	productIds := history.AdProductIds()
+
+	for _, order := range orders {
+		if ledger.Has(order.Id) {
+			continue
+		}
+
+		customerOrderId, err := i.importOrder(ctx, order, productIds)
+		if err != nil {
+			logger.Log.WithFields(logrus.Fields{
+				"error":   err,
+				"orderId": order.Id,
+			}).Log(logrus.ErrorLevel, "Не удалось загрузить заказ Avito")
+
+			continue
+		}
+
+		ledger.Add(order.Id, customerOrderId)
+
+		if err := ledger.Save(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// importOrder создает заказ покупателя для заказа Avito, если его еще нет в МойСклад.
+func (i *Importer) importOrder(ctx context.Context, order avitoapi.Order, productIds map[string]string) (string, error) {
+	externalCode := "avito-" + order.Id
+
+	existing, err := i.storage.FindCustomerOrder(ctx, externalCode)
+	if err != nil {
+		return "", err
+	}
+
+	if existing != nil {
+		return existing.Id, nil
+	}
+
+	customerOrder, err := newCustomerOrder(order, externalCode, productIds)
+	if err != nil {
+		return "", err
+	}
+
+	created, err := i.storage.CreateCustomerOrder(ctx, customerOrder)
+	if err != nil {
+		return "", err
+	}
+
+	logger.Log.WithFields(logrus.Fields{
+		"orderId":         order.Id,
+		"customerOrderId": created.Id,
+	}).Logf(logrus.InfoLevel, "Создали заказ покупателя %s по заказу Avito", created.Name)
+
+	return created.Id, nil
+}
+
+// newCustomerOrder формирует заказ покупателя МойСклад. Если какой-то товар заказа не удалось
+// сопоставить с товаром МойСклад, заказ не создается, чтобы не зарезервировать его частично.
+func newCustomerOrder(order avitoapi.Order, externalCode string, productIds map[string]string) (storage.CustomerOrder, error) {
+	params := config.Config.Orders
+
+	customerOrder := storage.CustomerOrder{
+		ExternalCode: externalCode,
+		Description:  "Заказ Авито Доставки " + textutil.FirstNotEmpty(order.MarketplaceId, order.Id),
+		Organization: storage.NewRef("organization", params.Organization),
+		Agent:        storage.NewRef("counterparty", params.Counterparty),
+		Positions:    make([]storage.CustomerOrderPosition, 0, len(order.Items)),
+	}
+
+	if order.CreatedAt != "" {
+		customerOrder.Description += " от " + order.CreatedAt
+	}
+
+	// Резерв товара возможен только на складе.
+	store := storage.NewRef("store", params.Store)
+	customerOrder.Store = &store
+
+	unknown := make([]string, 0)
+
+	for _, item := range order.Items {
+		productId, ok := productIds[item.AvitoId]
+		if !ok {
+			unknown = append(unknown, fmt.Sprintf("%s (%s)", item.Title, item.AvitoId))
+			continue
+		}
+
+		customerOrder.Positions = append(customerOrder.Positions, storage.CustomerOrderPosition{
+			Quantity:   float64(item.Count),
+			Price:      item.Prices.Price * 100,
+			Reserve:    float64(item.Count),
+			Assortment: storage.NewRef("product", productId),
+		})
+	}
+
+	if len(unknown) > 0 {
+		return customerOrder, fmt.Errorf("не найдены товары МойСклад для объявлений: %s", strings.Join(unknown, ", "))
+	}
+
+	return customerOrder, nil
+}
@@ -0,0 +1,191 @@
+package orders
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/KirillKhitev/carat_export/internal/avitoapi"
+	"github.com/KirillKhitev/carat_export/internal/config"
+	"github.com/KirillKhitev/carat_export/internal/history"
+	"github.com/KirillKhitev/carat_export/internal/report"
+	"github.com/KirillKhitev/carat_export/internal/storage"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// stub заглушка API Avito и МойСклад: заказы Avito и созданные заказы покупателей.
+type stub struct {
+	m       sync.Mutex
+	orders  []avitoapi.Order
+	created []storage.CustomerOrder
+	// existing внешние коды заказов покупателей, которые уже есть в МойСклад.
+	existing map[string]string
+}
+
+func (s *stub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	switch {
+	case r.URL.Path == "/token":
+		fmt.Fprint(w, `{"access_token":"token","expires_in":86400}`)
+	case r.URL.Path == "/order-management/1/orders":
+		json.NewEncoder(w).Encode(map[string]any{"orders": s.orders, "hasMore": false})
+	case r.URL.Path == "/api/entity/customerorder" && r.Method == http.MethodGet:
+		externalCode := strings.TrimPrefix(r.URL.Query().Get("filter"), "externalCode=")
+		rows := make([]storage.CustomerOrder, 0)
+
+		if id, ok := s.existing[externalCode]; ok {
+			rows = append(rows, storage.CustomerOrder{Id: id, ExternalCode: externalCode})
+		}
+
+		json.NewEncoder(w).Encode(map[string]any{"rows": rows})
+	case r.URL.Path == "/api/entity/customerorder" && r.Method == http.MethodPost:
+		var order storage.CustomerOrder
+		json.NewDecoder(r.Body).Decode(&order)
+
+		order.Id = fmt.Sprintf("created-%d", len(s.created)+1)
+		s.created = append(s.created, order)
+		s.existing[order.ExternalCode] = order.Id
+
+		json.NewEncoder(w).Encode(order)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// setup настраивает выгрузку на заглушку: объявление Avito 1001 выгружено для товара product-1.
+func setup(t *testing.T, s *stub) {
+	t.Helper()
+
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+
+	old := config.Config
+	t.Cleanup(func() { config.Config = old })
+
+	config.Config.HistoryDir = t.TempDir()
+	config.Config.AvitoApiUrl = srv.URL
+	config.Config.AvitoClientId = "id"
+	config.Config.AvitoClientSecret = "secret"
+	config.Config.MoySkladUrl = srv.URL + "/api/"
+	config.Config.Feeds = nil
+	config.Config.Orders = config.OrdersParams{
+		Interval:     60,
+		Organization: "organization",
+		Counterparty: "counterparty",
+		Store:        "store",
+	}
+
+	runs, err := history.LoadRuns()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	run := history.Run{}
+	run.SetFeed(config.DefaultFeedName, history.FeedRun{
+		Ads: map[string]report.AvitoStatus{"product-1": {AvitoId: "1001"}},
+	})
+	runs.Add(run)
+
+	if err := runs.Save(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func orderWithItem(id string, avitoId string) avitoapi.Order {
+	item := avitoapi.OrderItem{AvitoId: avitoId, Title: "Монета", Count: 2}
+	item.Prices.Price = 1500
+
+	return avitoapi.Order{Id: id, Items: []avitoapi.OrderItem{item}}
+}
+
+func TestImportIsIdempotent(t *testing.T) {
+	s := &stub{
+		orders: []avitoapi.Order{
+			orderWithItem("new", "1001"),
+			orderWithItem("unknown", "9999"),
+			orderWithItem("existing", "1001"),
+		},
+		existing: map[string]string{"avito-existing": "customer-order"},
+	}
+	setup(t, s)
+
+	importer := NewImporter()
+
+	for i := 0; i < 2; i++ {
+		if err := importer.Import(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(s.created) != 1 || s.created[0].ExternalCode != "avito-new" {
+		t.Fatalf("created = %+v, want one order avito-new", s.created)
+	}
+
+	ledger, err := history.LoadLedger(LedgerName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{"new": "created-1", "existing": "customer-order"}
+	if len(ledger.Entries) != len(want) {
+		t.Fatalf("ledger = %+v, want %v", ledger.Entries, want)
+	}
+
+	for orderId, customerOrderId := range want {
+		if entry, ok := ledger.Get(orderId); !ok || entry.Value != customerOrderId {
+			t.Errorf("ledger[%s] = %+v, want %s", orderId, entry, customerOrderId)
+		}
+	}
+}
+
+func TestImportSkipsLedgerOrders(t *testing.T) {
+	s := &stub{
+		orders:   []avitoapi.Order{orderWithItem("done", "1001")},
+		existing: map[string]string{},
+	}
+	setup(t, s)
+
+	ledger, err := history.LoadLedger(LedgerName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ledger.Add("done", "customer-order")
+
+	if err := ledger.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := NewImporter().Import(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(s.created) != 0 {
+		t.Fatalf("created = %+v, want none", s.created)
+	}
+}
+
+func TestNewCustomerOrderReservesOnStore(t *testing.T) {
+	setup(t, &stub{existing: map[string]string{}})
+
+	order, err := newCustomerOrder(orderWithItem("1", "1001"), "avito-1", map[string]string{"1001": "product-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if order.Store == nil || !strings.HasSuffix(order.Store.Meta.Href, "/store/store") {
+		t.Fatalf("store = %+v, want reserve store", order.Store)
+	}
+
+	position := order.Positions[0]
+	if position.Quantity != 2 || position.Reserve != 2 || position.Price != 150000 {
+		t.Fatalf("position = %+v", position)
+	}
+}
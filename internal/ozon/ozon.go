@@ -0,0 +1,408 @@
+package ozon
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/KirillKhitev/carat_export/internal/config"
+	"github.com/KirillKhitev/carat_export/internal/description"
+	"github.com/KirillKhitev/carat_export/internal/history"
+	"github.com/KirillKhitev/carat_export/internal/images"
+	"github.com/KirillKhitev/carat_export/internal/logger"
+	"github.com/KirillKhitev/carat_export/internal/ozonapi"
+	"github.com/KirillKhitev/carat_export/internal/report"
+	"github.com/KirillKhitev/carat_export/internal/storage"
+	"github.com/sirupsen/logrus"
+	"sort"
+	"strconv"
+)
+
+// Журналы синхронизации с Ozon.
+const (
+	// CardsLedgerName offer_id -> хеш последней отправленной карточки.
+	CardsLedgerName = "ozon_cards"
+	// TasksLedgerName задачи импорта, результаты которых еще не получены.
+	TasksLedgerName = "ozon_tasks"
+	// ResultsLedgerName offer_id -> результат последнего импорта карточки.
+	ResultsLedgerName = "ozon_results"
+)
+
+const (
+	taskPending = "pending"
+	// descriptionAttributeId характеристика Ozon «Аннотация» с описанием товара.
+	descriptionAttributeId = 4191
+	maxImages              = 15
+	defaultVat             = "0"
+)
+
+// Exporter синхронизирует карточки, цены и остатки товаров с Ozon через Seller API.
+type Exporter struct {
+	api *ozonapi.Client
+}
+
+func NewExporter() *Exporter {
+	return &Exporter{
+		api: ozonapi.NewClient(),
+	}
+}
+
+// Enabled показывает, настроена ли синхронизация с Ozon.
+func Enabled() bool {
+	return config.Config.Ozon.Enabled()
+}
+
+// OfferId возвращает артикул товара для Ozon: артикул МойСклад, а если он не заполнен - ID товара.
+func OfferId(p storage.Product) string {
+	if p.Article != "" {
+		return p.Article
+	}
+
+	return p.ID
+}
+
+// Sync получает результаты прошлых задач импорта, отправляет изменившиеся карточки и
+// обновляет цены и остатки товаров, карточки которых отправлены в Ozon. Если остатки по складам
+// не получены (storesLoaded), остатки в Ozon не меняются, чтобы не обнулить их из-за временной
+// ошибки МойСклад. Возвращает результат по каждому товару: ID товара -> причина. Ошибка отправки
+// карточек возвращается после обновления цен и остатков, товары неотправленных карточек
+// в результат не попадают.
+func (e *Exporter) Sync(ctx context.Context, products map[string]storage.Product, storesLoaded bool) (map[string]report.Reason, error) {
+	cards, err := history.LoadLedger(CardsLedgerName)
+	if err != nil {
+		return nil, err
+	}
+
+	tasks, err := history.LoadLedger(TasksLedgerName)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := history.LoadLedger(ResultsLedgerName)
+	if err != nil {
+		return nil, err
+	}
+
+	e.checkTasks(ctx, tasks, cards, results)
+
+	reasons := make(map[string]report.Reason, len(products))
+
+	importErr := e.importCards(ctx, products, cards, tasks, reasons)
+
+	if err := e.updatePrices(ctx, products, reasons, cards); err != nil {
+		logger.Log.WithFields(logrus.Fields{
+			"error": err,
+		}).Log(logrus.ErrorLevel, "Ошибка при обновлении цен Ozon")
+	}
+
+	if !storesLoaded {
+		logger.Log.Log(logrus.WarnLevel, "Остатки по складам не получены, остатки Ozon не обновляем")
+	} else if err := e.updateStocks(ctx, products, reasons, cards); err != nil {
+		logger.Log.WithFields(logrus.Fields{
+			"error": err,
+		}).Log(logrus.ErrorLevel, "Ошибка при обновлении остатков Ozon")
+	}
+
+	for _, ledger := range []*history.Ledger{cards, tasks, results} {
+		if err := ledger.Save(); err != nil {
+			return nil, err
+		}
+	}
+
+	if importErr != nil {
+		return reasons, fmt.Errorf("ошибка при отправке карточек товаров в Ozon: %w", importErr)
+	}
+
+	return reasons, nil
+}
+
+// checkTasks записывает результаты завершенных задач импорта по каждой карточке. Карточки
+// с ошибками будут отправлены повторно.
+func (e *Exporter) checkTasks(ctx context.Context, tasks, cards, results *history.Ledger) {
+	for _, id := range tasks.WithValue(taskPending) {
+		taskId, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			tasks.Delete(id)
+			continue
+		}
+
+		items, err := e.api.ImportInfo(ctx, taskId)
+		if err != nil {
+			logger.Log.WithFields(logrus.Fields{
+				"error":  err,
+				"taskId": taskId,
+			}).Log(logrus.ErrorLevel, "Ошибка при получении результатов импорта Ozon")
+
+			continue
+		}
+
+		done := true
+
+		for _, item := range items {
+			if !item.Done() {
+				done = false
+				continue
+			}
+
+			if item.Status == ozonapi.StatusImported && len(item.Errors) == 0 {
+				results.Add(item.OfferId, fmt.Sprintf("%s: %d", item.Status, item.ProductId))
+				continue
+			}
+
+			results.Add(item.OfferId, fmt.Sprintf("%s: %s", item.Status, item.ErrorMessage()))
+			cards.Delete(item.OfferId)
+
+			logger.Log.WithFields(logrus.Fields{
+				"offerId": item.OfferId,
+				"errors":  item.ErrorMessage(),
+			}).Log(logrus.WarnLevel, "Ozon не принял карточку товара")
+		}
+
+		if done {
+			tasks.Delete(id)
+		}
+	}
+}
+
+// importCards отправляет карточки, которые изменились с прошлой отправки, и записывает
+// в reasons результат по товарам. Если отправка не удалась, товары неотправленных карточек
+// убираются из reasons.
+func (e *Exporter) importCards(ctx context.Context, products map[string]storage.Product, cards, tasks *history.Ledger, reasons map[string]report.Reason) error {
+	items := make([]ozonapi.ImportItem, 0)
+	hashes := make(map[string]string)
+	productIds := make(map[string]string)
+	renderer := description.NewRenderer()
+
+	for _, p := range products {
+		item, reason := newImportItem(p, renderer)
+		reasons[p.ID] = reason
+
+		if reason != report.ReasonExported {
+			continue
+		}
+
+		hash, err := cardHash(item)
+		if err != nil {
+			return err
+		}
+
+		if entry, ok := cards.Get(item.OfferId); ok && entry.Value == hash {
+			continue
+		}
+
+		items = append(items, item)
+		hashes[item.OfferId] = hash
+		productIds[item.OfferId] = p.ID
+	}
+
+	for start := 0; start < len(items); start += ozonapi.MaxImportItems {
+		batch := items[start:min(start+ozonapi.MaxImportItems, len(items))]
+
+		taskId, err := e.api.ImportProducts(ctx, batch)
+		if err != nil {
+			for _, item := range items[start:] {
+				delete(reasons, productIds[item.OfferId])
+			}
+
+			return err
+		}
+
+		tasks.Add(strconv.FormatInt(taskId, 10), taskPending)
+
+		for _, item := range batch {
+			cards.Add(item.OfferId, hashes[item.OfferId])
+		}
+
+		logger.Log.Logf(logrus.InfoLevel, "Отправили в Ozon карточек товаров: %d, задача %d", len(batch), taskId)
+	}
+
+	return nil
+}
+
+// newImportItem формирует карточку товара. Товары без категории Ozon, цены или изображений
+// не отправляются, для них возвращается причина.
+func newImportItem(p storage.Product, renderer *description.Renderer) (ozonapi.ImportItem, report.Reason) {
+	params := config.Config.Ozon
+
+	category, ok := params.Category(p.Folder)
+	price := p.PriceByType(params.PriceType)
+	imagesParams := config.Config.Images
+	imagesParams.Max = maxImages
+	productImages := images.Prepare(p, imagesParams)
+
+	switch {
+	case !ok:
+		return ozonapi.ImportItem{}, report.ReasonFeedFilter
+	case price <= 0:
+		return ozonapi.ImportItem{}, report.ReasonNoPrice
+	case len(productImages) == 0:
+		return ozonapi.ImportItem{}, report.ReasonNoImages
+	}
+
+	item := ozonapi.ImportItem{
+		OfferId:               OfferId(p),
+		Name:                  p.Name,
+		Price:                 strconv.Itoa(price),
+		CurrencyCode:          "RUB",
+		Vat:                   params.Vat,
+		DescriptionCategoryId: category.DescriptionCategoryId,
+		TypeId:                category.TypeId,
+		Barcode:               p.Barcode(),
+		Images:                make([]string, 0, len(productImages)-1),
+		PrimaryImage:          productImages[0].Url,
+		Height:                params.Dimensions.Height,
+		Width:                 params.Dimensions.Width,
+		Depth:                 params.Dimensions.Depth,
+		DimensionUnit:         "mm",
+		Weight:                params.Dimensions.Weight,
+		WeightUnit:            "g",
+	}
+
+	if item.Vat == "" {
+		item.Vat = defaultVat
+	}
+
+	for _, img := range productImages[1:] {
+		item.Images = append(item.Images, img.Url)
+	}
+
+	text, err := renderer.Render(p)
+	if err != nil {
+		text = renderer.RenderDefault(p)
+	}
+
+	item.Attributes = append(item.Attributes, ozonapi.ProductAttribute{
+		Id:     descriptionAttributeId,
+		Values: []ozonapi.AttributeValue{{Value: text}},
+	})
+
+	ids := make([]string, 0, len(category.Attributes))
+	for id := range category.Attributes {
+		ids = append(ids, id)
+	}
+
+	// Характеристики отправляются в постоянном порядке, чтобы хеш карточки не менялся.
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		attributeId, err := strconv.ParseInt(id, 10, 64)
+		value := p.Attributes[category.Attributes[id]]
+
+		if err != nil || value == "" {
+			continue
+		}
+
+		item.Attributes = append(item.Attributes, ozonapi.ProductAttribute{
+			Id:     attributeId,
+			Values: []ozonapi.AttributeValue{{Value: value}},
+		})
+	}
+
+	return item, report.ReasonExported
+}
+
+// cardHash вычисляет хеш карточки, чтобы не отправлять неизменившиеся карточки повторно.
+func cardHash(item ozonapi.ImportItem) (string, error) {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// updatePrices обновляет цены выгружаемых товаров, карточки которых уже отправлены в Ozon.
+func (e *Exporter) updatePrices(ctx context.Context, products map[string]storage.Product, reasons map[string]report.Reason, cards *history.Ledger) error {
+	prices := make([]ozonapi.Price, 0, len(products))
+
+	for _, p := range products {
+		if reasons[p.ID] != report.ReasonExported || !cards.Has(OfferId(p)) {
+			continue
+		}
+
+		prices = append(prices, ozonapi.Price{
+			OfferId:      OfferId(p),
+			Price:        strconv.Itoa(p.PriceByType(config.Config.Ozon.PriceType)),
+			CurrencyCode: "RUB",
+		})
+	}
+
+	for start := 0; start < len(prices); start += ozonapi.MaxPriceItems {
+		updated, err := e.api.UpdatePrices(ctx, prices[start:min(start+ozonapi.MaxPriceItems, len(prices))])
+		if err != nil {
+			return err
+		}
+
+		logUpdateErrors(updated, "Ozon не обновил цену товара")
+	}
+
+	return nil
+}
+
+// updateStocks обновляет остатки товаров на складах Ozon по остаткам соответствующих складов
+// МойСклад. Остатки отправляются только для карточек из журнала: выгружаемым товарам - остатки
+// складов, карточкам товаров, которые больше не выгружаются, - нулевой остаток.
+func (e *Exporter) updateStocks(ctx context.Context, products map[string]storage.Product, reasons map[string]report.Reason, cards *history.Ledger) error {
+	warehouses := config.Config.Ozon.Warehouses
+	if len(warehouses) == 0 {
+		return nil
+	}
+
+	stocks := make([]ozonapi.Stock, 0)
+	current := make(map[string]struct{}, len(products))
+
+	for _, p := range products {
+		offerId := OfferId(p)
+		if reasons[p.ID] != report.ReasonExported || !cards.Has(offerId) {
+			continue
+		}
+
+		current[offerId] = struct{}{}
+
+		for store, warehouseId := range warehouses {
+			stocks = append(stocks, ozonapi.Stock{
+				OfferId:     offerId,
+				Stock:       max(int(p.Stores[store]), 0),
+				WarehouseId: warehouseId,
+			})
+		}
+	}
+
+	for offerId := range cards.Entries {
+		if _, ok := current[offerId]; ok {
+			continue
+		}
+
+		for _, warehouseId := range warehouses {
+			stocks = append(stocks, ozonapi.Stock{OfferId: offerId, WarehouseId: warehouseId})
+		}
+	}
+
+	for start := 0; start < len(stocks); start += ozonapi.MaxStockItems {
+		updated, err := e.api.UpdateStocks(ctx, stocks[start:min(start+ozonapi.MaxStockItems, len(stocks))])
+		if err != nil {
+			return err
+		}
+
+		logUpdateErrors(updated, "Ozon не обновил остаток товара")
+	}
+
+	return nil
+}
+
+func logUpdateErrors(results []ozonapi.UpdateResult, message string) {
+	for _, result := range results {
+		if result.Updated {
+			continue
+		}
+
+		logger.Log.WithFields(logrus.Fields{
+			"offerId":     result.OfferId,
+			"warehouseId": result.WarehouseId,
+			"errors":      result.ErrorMessage(),
+		}).Log(logrus.WarnLevel, message)
+	}
+}
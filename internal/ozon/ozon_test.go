@@ -0,0 +1,296 @@
+package ozon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/KirillKhitev/carat_export/internal/config"
+	"github.com/KirillKhitev/carat_export/internal/history"
+	"github.com/KirillKhitev/carat_export/internal/ozonapi"
+	"github.com/KirillKhitev/carat_export/internal/report"
+	"github.com/KirillKhitev/carat_export/internal/storage"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// seller заглушка Ozon Seller API: запоминает отправленные карточки, цены и остатки, результаты
+// задач импорта задаются в failed.
+type seller struct {
+	m       sync.Mutex
+	imports [][]string
+	prices  []ozonapi.Price
+	stocks  []ozonapi.Stock
+	// failed offer_id карточек, которые Ozon не принимает.
+	failed map[string]bool
+	// importDown - отправка карточек возвращает ошибку сервера.
+	importDown bool
+}
+
+func (s *seller) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if r.Header.Get("Client-Id") != "client" || r.Header.Get("Api-Key") != "key" {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"code":16,"message":"Invalid Api-Key"}`)
+		return
+	}
+
+	var body struct {
+		Items  []ozonapi.ImportItem `json:"items"`
+		TaskId int                  `json:"task_id"`
+		Stocks []ozonapi.Stock      `json:"stocks"`
+		Prices []ozonapi.Price      `json:"prices"`
+	}
+	json.NewDecoder(r.Body).Decode(&body)
+
+	switch r.URL.Path {
+	case "/v3/product/import":
+		if s.importDown {
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(w, `{"code":13,"message":"internal error"}`)
+			return
+		}
+
+		offerIds := make([]string, 0, len(body.Items))
+		for _, item := range body.Items {
+			offerIds = append(offerIds, item.OfferId)
+		}
+
+		s.imports = append(s.imports, offerIds)
+		fmt.Fprintf(w, `{"result":{"task_id":%d}}`, len(s.imports))
+	case "/v1/product/import/info":
+		items := make([]ozonapi.ImportResult, 0)
+
+		for _, offerId := range s.imports[body.TaskId-1] {
+			result := ozonapi.ImportResult{OfferId: offerId, ProductId: 1, Status: ozonapi.StatusImported}
+			if s.failed[offerId] {
+				result = ozonapi.ImportResult{OfferId: offerId, Status: ozonapi.StatusFailed, Errors: []ozonapi.ItemError{{Message: "не указан бренд"}}}
+			}
+
+			items = append(items, result)
+		}
+
+		json.NewEncoder(w).Encode(map[string]any{"result": map[string]any{"items": items}})
+	case "/v2/products/stocks":
+		s.stocks = append(s.stocks, body.Stocks...)
+		fmt.Fprint(w, `{"result":[]}`)
+	case "/v1/product/import/prices":
+		s.prices = append(s.prices, body.Prices...)
+		fmt.Fprint(w, `{"result":[]}`)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// newSeller настраивает синхронизацию на заглушку Ozon с пустыми журналами.
+func newSeller(t *testing.T) *seller {
+	t.Helper()
+
+	s := &seller{failed: make(map[string]bool)}
+
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+
+	old := config.Config
+	t.Cleanup(func() { config.Config = old })
+
+	config.Config.HistoryDir = t.TempDir()
+	config.Config.Images = config.ImagesParams{}
+	config.Config.Ozon = config.OzonParams{
+		ApiUrl:     srv.URL,
+		ClientId:   "client",
+		ApiKey:     "key",
+		Warehouses: map[string]int64{"Основной": 5},
+		Categories: map[string]config.OzonCategory{"Монеты": {DescriptionCategoryId: 1, TypeId: 2}},
+	}
+
+	return s
+}
+
+func coin(article string, price int) storage.Product {
+	return storage.Product{
+		ID:      "id-" + article,
+		Article: article,
+		Name:    "Монета " + article,
+		Folder:  "Монеты",
+		Price:   price,
+		Stores:  map[string]float32{"Основной": 3},
+		Images:  []storage.Image{{Filename: article + ".jpg", Url: "https://example.com/" + article + ".jpg"}},
+	}
+}
+
+func syncProducts(t *testing.T, products ...storage.Product) map[string]report.Reason {
+	t.Helper()
+
+	return syncWithStores(t, true, products...)
+}
+
+func syncWithStores(t *testing.T, storesLoaded bool, products ...storage.Product) map[string]report.Reason {
+	t.Helper()
+
+	byId := make(map[string]storage.Product, len(products))
+	for _, p := range products {
+		byId[p.ID] = p
+	}
+
+	reasons, err := NewExporter().Sync(context.Background(), byId, storesLoaded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return reasons
+}
+
+func TestUnchangedCardsAreNotImported(t *testing.T) {
+	s := newSeller(t)
+
+	syncProducts(t, coin("A1", 1500), coin("A2", 2000))
+	syncProducts(t, coin("A1", 1500), coin("A2", 2000))
+
+	if len(s.imports) != 1 || len(s.imports[0]) != 2 {
+		t.Fatalf("imports = %v, want both cards sent once", s.imports)
+	}
+
+	syncProducts(t, coin("A1", 1500), coin("A2", 2500))
+
+	if len(s.imports) != 2 || len(s.imports[1]) != 1 || s.imports[1][0] != "A2" {
+		t.Fatalf("imports = %v, want only changed card A2 resent", s.imports)
+	}
+}
+
+func TestFailedCardIsResent(t *testing.T) {
+	s := newSeller(t)
+	s.failed["A2"] = true
+
+	syncProducts(t, coin("A1", 1500), coin("A2", 2000))
+	syncProducts(t, coin("A1", 1500), coin("A2", 2000))
+
+	if len(s.imports) != 2 || len(s.imports[1]) != 1 || s.imports[1][0] != "A2" {
+		t.Fatalf("imports = %v, want failed card A2 resent", s.imports)
+	}
+
+	results, err := history.LoadLedger(ResultsLedgerName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if entry, _ := results.Get("A2"); entry.Value != "failed: не указан бренд" {
+		t.Fatalf("result A2 = %q", entry.Value)
+	}
+
+	if entry, _ := results.Get("A1"); entry.Value != "imported: 1" {
+		t.Fatalf("result A1 = %q", entry.Value)
+	}
+}
+
+func TestDroppedCardsGetZeroStock(t *testing.T) {
+	s := newSeller(t)
+
+	syncProducts(t, coin("A1", 1500), coin("A2", 2000))
+	s.stocks = nil
+
+	syncProducts(t, coin("A1", 1500))
+
+	got := make(map[string]int)
+	for _, stock := range s.stocks {
+		got[stock.OfferId] = stock.Stock
+	}
+
+	if len(got) != 2 || got["A1"] != 3 || got["A2"] != 0 {
+		t.Fatalf("stocks = %v, want A1: 3, A2: 0", got)
+	}
+}
+
+func TestStocksAreSkippedWhenStoresFailed(t *testing.T) {
+	s := newSeller(t)
+
+	syncWithStores(t, false, coin("A1", 1500))
+
+	if len(s.stocks) != 0 {
+		t.Fatalf("stocks = %+v, want none without store stocks", s.stocks)
+	}
+
+	if len(s.imports) != 1 {
+		t.Fatalf("imports = %v, want cards sent anyway", s.imports)
+	}
+}
+
+func TestReasonsPerProduct(t *testing.T) {
+	newSeller(t)
+
+	noCategory := coin("A3", 1500)
+	noCategory.Folder = "Книги"
+
+	reasons := syncProducts(t, coin("A1", 1500), coin("A2", 0), noCategory)
+
+	want := map[string]report.Reason{
+		"id-A1": report.ReasonExported,
+		"id-A2": report.ReasonNoPrice,
+		"id-A3": report.ReasonFeedFilter,
+	}
+
+	for id, reason := range want {
+		if reasons[id] != reason {
+			t.Errorf("reasons[%s] = %s, want %s", id, reasons[id], reason)
+		}
+	}
+}
+
+func TestPricesAndStocksOnlyForSentCards(t *testing.T) {
+	s := newSeller(t)
+
+	noCategory := coin("A3", 1500)
+	noCategory.Folder = "Книги"
+
+	syncProducts(t, coin("A1", 1500), coin("A2", 0), noCategory)
+
+	if len(s.prices) != 1 || s.prices[0].OfferId != "A1" {
+		t.Fatalf("prices = %+v, want only sent card A1", s.prices)
+	}
+
+	if len(s.stocks) != 1 || s.stocks[0].OfferId != "A1" {
+		t.Fatalf("stocks = %+v, want only sent card A1", s.stocks)
+	}
+}
+
+func TestImportFailureIsExporterError(t *testing.T) {
+	s := newSeller(t)
+
+	syncProducts(t, coin("A1", 1500))
+
+	s.importDown = true
+	s.prices, s.stocks = nil, nil
+
+	reasons, err := NewExporter().Sync(context.Background(), map[string]storage.Product{
+		"id-A1": coin("A1", 1500),
+		"id-A2": coin("A2", 2000),
+	}, true)
+
+	if err == nil {
+		t.Fatal("expected import error")
+	}
+
+	if reason, ok := reasons["id-A2"]; ok {
+		t.Fatalf("reasons[id-A2] = %s, want unsent card left out of report", reason)
+	}
+
+	if reasons["id-A1"] != report.ReasonExported {
+		t.Fatalf("reasons[id-A1] = %s, want unchanged card exported", reasons["id-A1"])
+	}
+
+	if len(s.prices) != 1 || s.prices[0].OfferId != "A1" {
+		t.Fatalf("prices = %+v, want prices of sent card A1 updated anyway", s.prices)
+	}
+
+	cards, err := history.LoadLedger(CardsLedgerName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cards.Has("A2") {
+		t.Fatal("unsent card A2 is recorded in ledger")
+	}
+}
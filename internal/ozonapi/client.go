@@ -0,0 +1,77 @@
+package ozonapi
+
+import (
+	"context"
+	"fmt"
+	"github.com/KirillKhitev/carat_export/internal/config"
+	"github.com/go-resty/resty/v2"
+	"strings"
+	"time"
+)
+
+// DefaultURL адрес Ozon Seller API по умолчанию.
+const DefaultURL = "https://api-seller.ozon.ru/"
+
+// Client клиент Ozon Seller API с авторизацией по Client-Id и Api-Key.
+type Client struct {
+	client   *resty.Client
+	clientId string
+	apiKey   string
+}
+
+// NewClient создает клиент с ключами доступа из настроек.
+func NewClient() *Client {
+	return &Client{
+		client:   resty.New(),
+		clientId: config.Config.Ozon.ClientId,
+		apiKey:   config.Config.Ozon.ApiKey,
+	}
+}
+
+// APIError ошибка, которую возвращает Ozon Seller API.
+type APIError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%d: %s", e.Code, e.Message)
+}
+
+// methodURL формирует адрес метода API.
+func methodURL(path string) string {
+	base := config.Config.Ozon.ApiUrl
+	if base == "" {
+		base = DefaultURL
+	}
+
+	return strings.TrimSuffix(base, "/") + "/" + strings.TrimPrefix(path, "/")
+}
+
+// request выполняет POST запрос к Ozon Seller API и разбирает ответ в result.
+func (c *Client) request(ctx context.Context, path string, body any, result any) error {
+	contextWithTimeout, cancel := context.WithTimeout(ctx, time.Duration(30*time.Second))
+	defer cancel()
+
+	var responseErr APIError
+
+	response, err := c.client.R().
+		SetContext(contextWithTimeout).
+		ForceContentType("application/json").
+		SetHeader("Client-Id", c.clientId).
+		SetHeader("Api-Key", c.apiKey).
+		SetBody(body).
+		SetResult(result).
+		SetError(&responseErr).
+		Post(methodURL(path))
+
+	if err != nil {
+		return err
+	}
+
+	if response.IsError() {
+		return fmt.Errorf("%s: %s: %w", path, response.Status(), &responseErr)
+	}
+
+	return nil
+}
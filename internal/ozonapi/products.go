@@ -0,0 +1,187 @@
+package ozonapi
+
+import (
+	"context"
+	"strings"
+)
+
+// Лимиты количества товаров в одном запросе.
+const (
+	MaxImportItems = 100
+	MaxPriceItems  = 1000
+	MaxStockItems  = 100
+)
+
+// ProductAttribute значение характеристики карточки товара.
+type ProductAttribute struct {
+	Id     int64            `json:"id"`
+	Values []AttributeValue `json:"values"`
+}
+
+type AttributeValue struct {
+	Value string `json:"value"`
+}
+
+// ImportItem карточка товара для создания или обновления.
+type ImportItem struct {
+	OfferId               string             `json:"offer_id"`
+	Name                  string             `json:"name"`
+	Price                 string             `json:"price"`
+	CurrencyCode          string             `json:"currency_code"`
+	Vat                   string             `json:"vat"`
+	DescriptionCategoryId int64              `json:"description_category_id"`
+	TypeId                int64              `json:"type_id"`
+	Barcode               string             `json:"barcode,omitempty"`
+	Images                []string           `json:"images"`
+	PrimaryImage          string             `json:"primary_image,omitempty"`
+	Height                int                `json:"height"`
+	Width                 int                `json:"width"`
+	Depth                 int                `json:"depth"`
+	DimensionUnit         string             `json:"dimension_unit"`
+	Weight                int                `json:"weight"`
+	WeightUnit            string             `json:"weight_unit"`
+	Attributes            []ProductAttribute `json:"attributes"`
+}
+
+type importResponse struct {
+	Result struct {
+		TaskId int64 `json:"task_id"`
+	} `json:"result"`
+}
+
+// ImportProducts создает или обновляет карточки товаров и возвращает идентификатор задачи импорта.
+func (c *Client) ImportProducts(ctx context.Context, items []ImportItem) (int64, error) {
+	var result importResponse
+
+	body := map[string]any{"items": items}
+	if err := c.request(ctx, "v3/product/import", body, &result); err != nil {
+		return 0, err
+	}
+
+	return result.Result.TaskId, nil
+}
+
+// ItemError ошибка обработки товара.
+type ItemError struct {
+	Code    string `json:"code"`
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ImportResult результат импорта одной карточки.
+type ImportResult struct {
+	OfferId   string      `json:"offer_id"`
+	ProductId int64       `json:"product_id"`
+	Status    string      `json:"status"`
+	Errors    []ItemError `json:"errors"`
+}
+
+// Статусы карточки в задаче импорта.
+const (
+	StatusImported = "imported"
+	StatusFailed   = "failed"
+	StatusPending  = "pending"
+)
+
+// Done показывает, что обработка карточки завершена.
+func (r ImportResult) Done() bool {
+	return r.Status != StatusPending && r.Status != ""
+}
+
+// ErrorMessage возвращает ошибки карточки одной строкой.
+func (r ImportResult) ErrorMessage() string {
+	return joinErrors(r.Errors)
+}
+
+type importInfoResponse struct {
+	Result struct {
+		Items []ImportResult `json:"items"`
+		Total int            `json:"total"`
+	} `json:"result"`
+}
+
+// ImportInfo возвращает результаты задачи импорта по каждой карточке.
+func (c *Client) ImportInfo(ctx context.Context, taskId int64) ([]ImportResult, error) {
+	var result importInfoResponse
+
+	body := map[string]any{"task_id": taskId}
+	if err := c.request(ctx, "v1/product/import/info", body, &result); err != nil {
+		return nil, err
+	}
+
+	return result.Result.Items, nil
+}
+
+// Price цена товара.
+type Price struct {
+	OfferId      string `json:"offer_id"`
+	Price        string `json:"price"`
+	CurrencyCode string `json:"currency_code"`
+}
+
+// Stock остаток товара на складе Ozon.
+type Stock struct {
+	OfferId     string `json:"offer_id"`
+	Stock       int    `json:"stock"`
+	WarehouseId int64  `json:"warehouse_id"`
+}
+
+// UpdateResult результат обновления цены или остатка одного товара.
+type UpdateResult struct {
+	OfferId     string      `json:"offer_id"`
+	WarehouseId int64       `json:"warehouse_id,omitempty"`
+	Updated     bool        `json:"updated"`
+	Errors      []ItemError `json:"errors"`
+}
+
+// ErrorMessage возвращает ошибки обновления одной строкой.
+func (r UpdateResult) ErrorMessage() string {
+	return joinErrors(r.Errors)
+}
+
+type updateResponse struct {
+	Result []UpdateResult `json:"result"`
+}
+
+// UpdatePrices обновляет цены товаров.
+func (c *Client) UpdatePrices(ctx context.Context, prices []Price) ([]UpdateResult, error) {
+	var result updateResponse
+
+	body := map[string]any{"prices": prices}
+	if err := c.request(ctx, "v1/product/import/prices", body, &result); err != nil {
+		return nil, err
+	}
+
+	return result.Result, nil
+}
+
+// UpdateStocks обновляет остатки товаров на складах.
+func (c *Client) UpdateStocks(ctx context.Context, stocks []Stock) ([]UpdateResult, error) {
+	var result updateResponse
+
+	body := map[string]any{"stocks": stocks}
+	if err := c.request(ctx, "v2/products/stocks", body, &result); err != nil {
+		return nil, err
+	}
+
+	return result.Result, nil
+}
+
+func joinErrors(errors []ItemError) string {
+	messages := make([]string, 0, len(errors))
+
+	for _, e := range errors {
+		message := e.Message
+		if message == "" {
+			message = e.Code
+		}
+
+		if e.Field != "" {
+			message = e.Field + ": " + message
+		}
+
+		messages = append(messages, message)
+	}
+
+	return strings.Join(messages, "; ")
+}
@@ -0,0 +1,276 @@
+package report
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"github.com/KirillKhitev/carat_export/internal/config"
+	"github.com/KirillKhitev/carat_export/internal/fileutil"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	JSONFileName = "report.json"
+	CSVFileName  = "report.csv"
+)
+
+func init() {
+	config.ReserveRoute("/"+JSONFileName, "отчет о выгрузке в JSON")
+	config.ReserveRoute("/"+CSVFileName, "отчет о выгрузке в CSV")
+}
+
+// Reason код причины, по которой товар попал или не попал в выгрузку.
+type Reason string
+
+const (
+	ReasonExported      Reason = "exported"
+	ReasonNoExportFlag  Reason = "no_export_flag"
+	ReasonNoPrice       Reason = "no_price"
+	ReasonZeroStock     Reason = "zero_stock"
+	ReasonNoImages      Reason = "no_images"
+	ReasonImagesFailed  Reason = "images_failed"
+	ReasonValidationErr Reason = "validation_error"
+	ReasonFeedFilter    Reason = "feed_filter"
+	ReasonNotMatched    Reason = "not_matched"
+)
+
+// reasonMessages человекочитаемые описания причин для контент-менеджеров.
+var reasonMessages = map[Reason]string{
+	ReasonExported:      "Выгружен",
+	ReasonNoExportFlag:  "Не отмечен флаг «Выгружать на Авито»",
+	ReasonNoPrice:       "Не указана цена продажи",
+	ReasonZeroStock:     "Нет остатка",
+	ReasonNoImages:      "Нет изображений в МойСклад",
+	ReasonImagesFailed:  "Не удалось загрузить изображения",
+	ReasonValidationErr: "Ошибка проверки объявления",
+	ReasonFeedFilter:    "Не подходит под условия фида",
+	ReasonNotMatched:    "Не сопоставлен с карточкой маркетплейса",
+}
+
+// Message возвращает описание причины на русском языке.
+func (r Reason) Message() string {
+	if m, ok := reasonMessages[r]; ok {
+		return m
+	}
+
+	return string(r)
+}
+
+// AvitoStatus результат обработки объявления Avito по отчету автозагрузки.
+type AvitoStatus struct {
+	AvitoId  string   `json:"avito_id,omitempty"`
+	Url      string   `json:"url,omitempty"`
+	Status   string   `json:"status,omitempty"`
+	Errors   []string `json:"errors,omitempty"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// Item строка отчета по одному товару МойСклад в одном фиде. Для товаров, не прошедших
+// общий фильтр выгрузки, фид не указывается.
+type Item struct {
+	Feed     string  `json:"feed,omitempty"`
+	ID       string  `json:"id"`
+	Name     string  `json:"name"`
+	Article  string  `json:"article"`
+	Price    int     `json:"price"`
+	Stock    float32 `json:"stock"`
+	Exported bool    `json:"exported"`
+	Reason   Reason  `json:"reason"`
+	Message  string  `json:"message"`
+	Details  string  `json:"details,omitempty"`
+
+	Avito *AvitoStatus `json:"avito,omitempty"`
+}
+
+// Report отчет о выгрузке товаров за один запуск.
+type Report struct {
+	m         *sync.Mutex
+	CreatedAt time.Time `json:"created_at"`
+	Total     int       `json:"total"`
+	Exported  int       `json:"exported"`
+	Excluded  int       `json:"excluded"`
+	Items     []Item    `json:"items"`
+}
+
+func New() *Report {
+	return &Report{
+		m:         &sync.Mutex{},
+		CreatedAt: time.Now(),
+		Items:     make([]Item, 0),
+	}
+}
+
+// Add добавляет в отчет строку по товару.
+func (r *Report) Add(item Item) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	item.Exported = item.Reason == ReasonExported
+	if item.Message == "" {
+		item.Message = item.Reason.Message()
+	}
+
+	r.Items = append(r.Items, item)
+	r.Total++
+
+	if item.Exported {
+		r.Exported++
+	} else {
+		r.Excluded++
+	}
+}
+
+// Exclude исключает из выгрузки товар, ранее отмеченный как выгруженный. Если товар уже
+// исключен, к строке добавляются только подробности, а причина остается первой.
+func (r *Report) Exclude(feed string, id string, reason Reason, details string) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	for i, item := range r.Items {
+		if item.Feed != feed || item.ID != id {
+			continue
+		}
+
+		if !item.Exported {
+			r.Items[i].Details = joinDetails(item.Details, details)
+			continue
+		}
+
+		r.Items[i].Exported = false
+		r.Items[i].Reason = reason
+		r.Items[i].Message = reason.Message()
+		r.Items[i].Details = joinDetails(item.Details, details)
+		r.Exported--
+		r.Excluded++
+	}
+}
+
+// Warn добавляет к строке товара замечание, не влияющее на выгрузку.
+func (r *Report) Warn(feed string, id string, details string) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	for i, item := range r.Items {
+		if item.Feed == feed && item.ID == id {
+			r.Items[i].Details = joinDetails(item.Details, details)
+		}
+	}
+}
+
+// SetAvitoStatus добавляет к строке товара результат обработки объявления в Avito.
+func (r *Report) SetAvitoStatus(feed string, id string, status AvitoStatus) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	for i, item := range r.Items {
+		if item.Feed == feed && item.ID == id {
+			r.Items[i].Avito = &status
+		}
+	}
+}
+
+func joinDetails(details, add string) string {
+	if details == "" {
+		return add
+	}
+
+	return details + "; " + add
+}
+
+// Save сохраняет отчет в папку отчетов в форматах JSON и CSV.
+func (r *Report) Save() error {
+	if err := r.SaveJSON(JSONPath()); err != nil {
+		return err
+	}
+
+	return r.SaveCSV(CSVPath())
+}
+
+// JSONPath путь до JSON файла отчета.
+func JSONPath() string {
+	return filepath.Join(config.Config.ReportDir, JSONFileName)
+}
+
+// CSVPath путь до CSV файла отчета.
+func CSVPath() string {
+	return filepath.Join(config.Config.ReportDir, CSVFileName)
+}
+
+// SaveJSON сохраняет отчет в JSON файл.
+func (r *Report) SaveJSON(path string) error {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	data, err := json.MarshalIndent(r, "", "   ")
+	if err != nil {
+		return err
+	}
+
+	return fileutil.WriteAtomic(path, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+// SaveCSV сохраняет отчет в CSV файл.
+func (r *Report) SaveCSV(path string) error {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	return fileutil.WriteAtomic(path, func(f io.Writer) error {
+		return r.writeCSV(f)
+	})
+}
+
+func (r *Report) writeCSV(f io.Writer) error {
+	w := csv.NewWriter(f)
+
+	header := []string{
+		"feed", "id", "name", "article", "price", "stock", "exported", "reason", "message", "details",
+		"avito_id", "avito_url", "avito_status", "avito_errors", "avito_warnings",
+	}
+
+	if err := w.Write(header); err != nil {
+		return err
+	}
+
+	for _, item := range r.Items {
+		record := []string{
+			item.Feed,
+			item.ID,
+			item.Name,
+			item.Article,
+			strconv.Itoa(item.Price),
+			strconv.FormatFloat(float64(item.Stock), 'f', -1, 32),
+			strconv.FormatBool(item.Exported),
+			string(item.Reason),
+			item.Message,
+			item.Details,
+		}
+
+		avito := AvitoStatus{}
+		if item.Avito != nil {
+			avito = *item.Avito
+		}
+
+		record = append(record,
+			avito.AvitoId,
+			avito.Url,
+			avito.Status,
+			strings.Join(avito.Errors, " | "),
+			strings.Join(avito.Warnings, " | "),
+		)
+
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+
+	w.Flush()
+
+	return w.Error()
+}
@@ -0,0 +1,185 @@
+package site
+
+import (
+	"encoding/xml"
+	"github.com/KirillKhitev/carat_export/internal/fileutil"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ProductsDir папка страниц товаров внутри каталога.
+const ProductsDir = "products"
+
+// Page страница товара.
+type Page struct {
+	ID          string
+	Name        string
+	Price       int
+	Folder      string
+	Images      []string
+	Description template.HTML
+	// Summary описание простым текстом для OpenGraph.
+	Summary  string
+	AvitoURL string
+}
+
+// Path путь до страницы товара относительно корня каталога.
+func (p Page) Path() string {
+	return ProductsDir + "/" + p.ID + ".html"
+}
+
+// Folder группа товаров на главной странице.
+type Folder struct {
+	Name  string
+	Pages []Page
+}
+
+// Site каталог товаров. BaseURL - абсолютный адрес корня каталога.
+type Site struct {
+	Title     string
+	BaseURL   string
+	UpdatedAt time.Time
+	Pages     []Page
+}
+
+// URL возвращает абсолютный адрес страницы каталога.
+func (s Site) URL(path string) string {
+	return strings.TrimSuffix(s.BaseURL, "/") + "/" + path
+}
+
+// Folders группирует страницы товаров по группам МойСклад.
+func (s Site) Folders() []Folder {
+	byName := make(map[string][]Page)
+
+	for _, p := range s.Pages {
+		byName[p.Folder] = append(byName[p.Folder], p)
+	}
+
+	result := make([]Folder, 0, len(byName))
+
+	for name, pages := range byName {
+		sort.Slice(pages, func(i, j int) bool {
+			return pages[i].Name < pages[j].Name
+		})
+
+		result = append(result, Folder{Name: name, Pages: pages})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+
+	return result
+}
+
+// Generate сохраняет в dir главную страницу, страницы товаров и sitemap.xml. Страницы товаров,
+// которых больше нет в каталоге, удаляются.
+func Generate(dir string, s Site) error {
+	if err := os.MkdirAll(filepath.Join(dir, ProductsDir), 0755); err != nil {
+		return err
+	}
+
+	err := fileutil.WriteAtomic(filepath.Join(dir, "style.css"), func(w io.Writer) error {
+		_, err := io.WriteString(w, stylesheet)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := writeTemplate(filepath.Join(dir, "index.html"), indexTemplate, s); err != nil {
+		return err
+	}
+
+	current := make(map[string]struct{}, len(s.Pages))
+
+	for _, p := range s.Pages {
+		current[p.ID+".html"] = struct{}{}
+
+		data := struct {
+			Site
+			Page Page
+		}{s, p}
+
+		if err := writeTemplate(filepath.Join(dir, p.Path()), productTemplate, data); err != nil {
+			return err
+		}
+	}
+
+	if err := removeStalePages(filepath.Join(dir, ProductsDir), current); err != nil {
+		return err
+	}
+
+	return writeSitemap(filepath.Join(dir, "sitemap.xml"), s)
+}
+
+func writeTemplate(path string, tmpl *template.Template, data any) error {
+	return fileutil.WriteAtomic(path, func(w io.Writer) error {
+		return tmpl.Execute(w, data)
+	})
+}
+
+// removeStalePages удаляет страницы товаров, которых нет в current.
+func removeStalePages(dir string, current map[string]struct{}) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if _, ok := current[entry.Name()]; ok || entry.IsDir() || filepath.Ext(entry.Name()) != ".html" {
+			continue
+		}
+
+		if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod"`
+}
+
+type sitemap struct {
+	XMLName xml.Name     `xml:"urlset"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+// writeSitemap сохраняет sitemap.xml с главной страницей и страницами товаров.
+func writeSitemap(path string, s Site) error {
+	lastMod := s.UpdatedAt.Format("2006-01-02")
+
+	m := sitemap{
+		XMLNS: "http://www.sitemaps.org/schemas/sitemap/0.9",
+		URLs:  []sitemapURL{{Loc: s.URL(""), LastMod: lastMod}},
+	}
+
+	for _, p := range s.Pages {
+		m.URLs = append(m.URLs, sitemapURL{Loc: s.URL(p.Path()), LastMod: lastMod})
+	}
+
+	return fileutil.WriteAtomic(path, func(w io.Writer) error {
+		if _, err := io.WriteString(w, xml.Header); err != nil {
+			return err
+		}
+
+		enc := xml.NewEncoder(w)
+		enc.Indent("", "   ")
+
+		if err := enc.Encode(m); err != nil {
+			return err
+		}
+
+		return enc.Flush()
+	})
+}
@@ -0,0 +1,79 @@
+package site
+
+import "html/template"
+
+var funcs = template.FuncMap{
+	"first": func(images []string) string {
+		if len(images) == 0 {
+			return ""
+		}
+
+		return images[0]
+	},
+}
+
+var indexTemplate = template.Must(template.New("index").Funcs(funcs).Parse(`<!DOCTYPE html>
+<html lang="ru">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<meta property="og:type" content="website">
+<meta property="og:title" content="{{.Title}}">
+<meta property="og:url" content="{{.URL ""}}">
+<link rel="stylesheet" href="style.css">
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{range .Folders}}
+<section>
+<h2>{{if .Name}}{{.Name}}{{else}}Без группы{{end}}</h2>
+<ul class="products">
+{{range .Pages}}<li><a href="{{.Path}}">{{with first .Images}}<img src="{{.}}" alt="" loading="lazy">{{end}}<span>{{.Name}}</span> <strong>{{.Price}} ₽</strong></a></li>
+{{end}}</ul>
+</section>
+{{end}}
+</body>
+</html>
+`))
+
+var productTemplate = template.Must(template.New("product").Funcs(funcs).Parse(`<!DOCTYPE html>
+<html lang="ru">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Page.Name}} - {{.Title}}</title>
+<meta name="description" content="{{.Page.Summary}}">
+<meta property="og:type" content="product">
+<meta property="og:site_name" content="{{.Title}}">
+<meta property="og:title" content="{{.Page.Name}}">
+<meta property="og:description" content="{{.Page.Summary}}">
+<meta property="og:url" content="{{.URL .Page.Path}}">
+{{with first .Page.Images}}<meta property="og:image" content="{{.}}">
+{{end}}<meta property="product:price:amount" content="{{.Page.Price}}">
+<meta property="product:price:currency" content="RUB">
+<link rel="stylesheet" href="../style.css">
+</head>
+<body>
+<p><a href="../index.html">{{.Title}}</a>{{with .Page.Folder}} / {{.}}{{end}}</p>
+<h1>{{.Page.Name}}</h1>
+<div class="images">
+{{range .Page.Images}}<img src="{{.}}" alt="{{$.Page.Name}}">
+{{end}}</div>
+<p class="price">{{.Page.Price}} ₽</p>
+{{with .Page.AvitoURL}}<p><a class="buy" href="{{.}}">Купить на Avito</a></p>{{end}}
+<div class="description">{{.Page.Description}}</div>
+</body>
+</html>
+`))
+
+// stylesheet оформление страниц каталога.
+const stylesheet = `body { font-family: sans-serif; max-width: 1000px; margin: 0 auto; padding: 16px; color: #222; }
+a { color: #0a5dc2; text-decoration: none; }
+.products { list-style: none; padding: 0; display: grid; grid-template-columns: repeat(auto-fill, minmax(180px, 1fr)); gap: 16px; }
+.products img { width: 100%; aspect-ratio: 1; object-fit: cover; display: block; }
+.products span { display: block; margin: 4px 0; color: #222; }
+.images img { max-width: 100%; margin-bottom: 8px; }
+.price { font-size: 1.5em; font-weight: bold; }
+.buy { display: inline-block; padding: 10px 20px; background: #00aaff; color: #fff; border-radius: 6px; }
+`
@@ -0,0 +1,67 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"github.com/KirillKhitev/carat_export/internal/config"
+	"github.com/KirillKhitev/carat_export/internal/fileutil"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const FileName = "catalog_snapshot.json"
+
+// Product товар выгруженного каталога.
+type Product struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Article     string   `json:"article,omitempty"`
+	Folder      string   `json:"folder,omitempty"`
+	Price       int      `json:"price"`
+	Stock       float32  `json:"stock"`
+	Description string   `json:"description,omitempty"`
+	Images      []string `json:"images"`
+	AvitoURL    string   `json:"avito_url,omitempty"`
+	// Feeds выгрузки, в которые попал товар.
+	Feeds []string `json:"feeds"`
+}
+
+// Snapshot каталог товаров, выгруженных за последний успешный запуск.
+type Snapshot struct {
+	CreatedAt time.Time `json:"created_at"`
+	Products  []Product `json:"products"`
+}
+
+// Path путь до файла снимка каталога.
+func Path() string {
+	return filepath.Join(config.Config.HistoryDir, FileName)
+}
+
+// Save атомарно сохраняет снимок каталога.
+func (s Snapshot) Save(path string) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	return fileutil.WriteAtomic(path, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+// Load читает снимок каталога.
+func Load(path string) (*Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Snapshot{}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
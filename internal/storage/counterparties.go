@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"github.com/KirillKhitev/carat_export/internal/config"
+	"net/http"
+	"net/url"
+)
+
+// Counterparty контрагент МойСклад.
+type Counterparty struct {
+	Id           string `json:"id,omitempty"`
+	Name         string `json:"name"`
+	ExternalCode string `json:"externalCode,omitempty"`
+	CompanyType  string `json:"companyType,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+type CounterpartyListResponse struct {
+	Meta MetaList       `json:"meta"`
+	Rows []Counterparty `json:"rows"`
+}
+
+// Task задача МойСклад.
+type Task struct {
+	Id          string   `json:"id,omitempty"`
+	Description string   `json:"description"`
+	Assignee    MetaRef  `json:"assignee"`
+	Agent       *MetaRef `json:"agent,omitempty"`
+	DueToDate   string   `json:"dueToDate,omitempty"`
+}
+
+// FindCounterparty ищет контрагента по внешнему коду.
+func (s *MoySklad) FindCounterparty(ctx context.Context, externalCode string) (*Counterparty, error) {
+	u := fmt.Sprintf("%sentity/counterparty?filter=%s", config.Config.MoySkladUrl, url.QueryEscape("externalCode="+externalCode))
+	response := queryData[CounterpartyListResponse](s, ctx, u)
+
+	if response.Error != nil {
+		return nil, response.Error
+	}
+
+	if len(response.Response.Rows) == 0 {
+		return nil, nil
+	}
+
+	return &response.Response.Rows[0], nil
+}
+
+// CreateCounterparty создает контрагента.
+func (s *MoySklad) CreateCounterparty(ctx context.Context, counterparty Counterparty) (Counterparty, error) {
+	u := fmt.Sprintf("%sentity/counterparty", config.Config.MoySkladUrl)
+	response := sendData[Counterparty](s, ctx, http.MethodPost, u, counterparty)
+
+	return response.Response, response.Error
+}
+
+// CreateTask создает задачу.
+func (s *MoySklad) CreateTask(ctx context.Context, task Task) (Task, error) {
+	u := fmt.Sprintf("%sentity/task", config.Config.MoySkladUrl)
+	response := sendData[Task](s, ctx, http.MethodPost, u, task)
+
+	return response.Response, response.Error
+}
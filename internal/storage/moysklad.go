@@ -7,10 +7,11 @@ import (
 	"fmt"
 	"github.com/KirillKhitev/carat_export/internal/config"
 	"github.com/KirillKhitev/carat_export/internal/logger"
+	"github.com/KirillKhitev/carat_export/internal/report"
+	"github.com/KirillKhitev/carat_export/internal/timeutil"
 	"github.com/go-resty/resty/v2"
 	"github.com/sirupsen/logrus"
 	"os"
-	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -21,6 +22,10 @@ type MoySklad struct {
 	client   *resty.Client
 	m        *sync.RWMutex
 	Products map[string]Product
+	Excluded map[string]ExcludedProduct
+	// StoresLoaded показывает, что остатки по складам получены полностью. Если нет, остатки
+	// товаров по складам неизвестны и не должны отправляться на маркетплейсы.
+	StoresLoaded bool
 }
 
 func NewMoySklad() *MoySklad {
@@ -28,9 +33,16 @@ func NewMoySklad() *MoySklad {
 		client:   resty.New(),
 		m:        &sync.RWMutex{},
 		Products: make(map[string]Product),
+		Excluded: make(map[string]ExcludedProduct),
 	}
 }
 
+// ExcludedProduct товар, не прошедший фильтр выгрузки, с причиной исключения.
+type ExcludedProduct struct {
+	Product Product
+	Reason  report.Reason
+}
+
 type Product struct {
 	ID             string                `json:"id"`
 	Name           string                `json:"name"`
@@ -38,11 +50,26 @@ type Product struct {
 	Description    string                `json:"description"`
 	ImagesResponse ProductImagesResponse `json:"images"`
 	VideoURL       string                `json:"video_url"`
+	Folder         string                `json:"pathName"`
 	Images         []Image               `json:"-"`
 	ExportAvito    bool                  `json:"-"`
 	AvitoId        string                `json:"-"`
+	Address        string                `json:"-"`
+	Condition      string                `json:"-"`
+	AdType         string                `json:"-"`
+	ContactPhone   string                `json:"-"`
+	ManagerName    string                `json:"-"`
+	ContactMethod  string                `json:"-"`
+	ListingFee     string                `json:"-"`
+	AdStatus       string                `json:"-"`
+	DateBegin      time.Time             `json:"-"`
+	DateEnd        time.Time             `json:"-"`
 	Price          int                   `json:"-"`
+	Prices         map[string]int        `json:"-"`
 	Stock          float32               `json:"stock"`
+	Stores         map[string]float32    `json:"-"`
+	Attributes     map[string]string     `json:"-"`
+	Barcodes       []map[string]string   `json:"barcodes"`
 }
 
 type Image struct {
@@ -79,8 +106,26 @@ type MetaImage struct {
 	Href         string `json:"href,omitempty"`
 	DownloadHref string `json:"downloadHref,omitempty"`
 }
+type StockByStoreResponse struct {
+	Meta MetaList          `json:"meta"`
+	Rows []StockByStoreRow `json:"rows"`
+}
+
+type StockByStoreRow struct {
+	Meta         MetaImage    `json:"meta"`
+	StockByStore []StoreStock `json:"stockByStore"`
+}
+
+type StoreStock struct {
+	Name  string  `json:"name"`
+	Stock float32 `json:"stock"`
+}
+
 type SalePrice struct {
-	Value float64 `json:"value"`
+	Value     float64 `json:"value"`
+	PriceType struct {
+		Name string `json:"name"`
+	} `json:"priceType"`
 }
 
 type MetaList struct {
@@ -117,25 +162,100 @@ func (p *Product) UnmarshalJSON(data []byte) (err error) {
 		return
 	}
 
+	p.Attributes = make(map[string]string, len(aliasValue.Attributes))
+
 	for _, v := range aliasValue.Attributes {
 		val := fmt.Sprintf("%v", v.Value)
+		p.Attributes[v.Name] = attributeName(v.Value)
 
 		switch v.Name {
 		case `Выгружать на Авито`:
 			val, _ := strconv.ParseBool(val)
 			p.ExportAvito = val
-		case `AvitoId`:
+		case config.Config.WriteBack.AvitoIdAttribute():
 			p.AvitoId = val
 		case `VideoURL`:
 			p.VideoURL = val
+		case `Адрес на Авито`:
+			p.Address = val
+		case `Состояние`:
+			p.Condition = attributeName(v.Value)
+		case `Тип объявления`:
+			p.AdType = attributeName(v.Value)
+		case `Телефон для связи`:
+			p.ContactPhone = val
+		case `Менеджер`:
+			p.ManagerName = attributeName(v.Value)
+		case `Способ связи`:
+			p.ContactMethod = attributeName(v.Value)
+		case `Платное размещение`:
+			p.ListingFee = attributeName(v.Value)
+		case `Продвижение на Авито`:
+			p.AdStatus = attributeName(v.Value)
+		case `Дата начала публикации`:
+			p.DateBegin = parseAttributeDate(p.ID, v.Name, val)
+		case `Дата окончания публикации`:
+			p.DateEnd = parseAttributeDate(p.ID, v.Name, val)
 		}
 	}
 
-	p.Price = int(aliasValue.SalePrices[0].Value) / 100
+	p.Prices = make(map[string]int, len(aliasValue.SalePrices))
+	for _, price := range aliasValue.SalePrices {
+		p.Prices[price.PriceType.Name] = int(price.Value) / 100
+	}
+
+	if len(aliasValue.SalePrices) > 0 {
+		p.Price = int(aliasValue.SalePrices[0].Value) / 100
+	}
 
 	return
 }
 
+// parseAttributeDate разбирает дату из доп. поля товара. Ошибка пишется в лог, дата остается
+// пустой, чтобы товар выгрузился без расписания.
+func parseAttributeDate(productId, name, value string) time.Time {
+	date, err := time.ParseInLocation(DateTimeFormat, value, timeutil.Moscow)
+	if err != nil {
+		logger.Log.WithFields(logrus.Fields{
+			"error":     err,
+			"productId": productId,
+			"attribute": name,
+			"value":     value,
+		}).Log(logrus.WarnLevel, "Не удалось разобрать дату из доп. поля товара")
+
+		return time.Time{}
+	}
+
+	return date
+}
+
+// PriceByType возвращает цену товара в рублях по названию типа цены. Пустое название -
+// основная (первая) цена продажи.
+func (p Product) PriceByType(priceType string) int {
+	if priceType == "" {
+		return p.Price
+	}
+
+	return p.Prices[priceType]
+}
+
+// Barcode возвращает штрихкод товара, предпочитая EAN13. Пустая строка - у товара нет штрихкодов.
+func (p Product) Barcode() string {
+	for _, barcode := range p.Barcodes {
+		if code, ok := barcode["ean13"]; ok {
+			return code
+		}
+	}
+
+	for _, barcode := range p.Barcodes {
+		for _, code := range barcode {
+			return code
+		}
+	}
+
+	return ""
+}
+
 // GetProductsList формирует список товаров
 func (s *MoySklad) GetProductsList(ctx context.Context) error {
 	offset := 0
@@ -157,12 +277,18 @@ func (s *MoySklad) GetProductsList(ctx context.Context) error {
 		needQuery = len(response.Response.Rows) >= response.Response.Meta.Limit
 		offset = offset + response.Response.Meta.Limit
 
-		for _, product := range filterProducts(response.Response.Rows) {
-			s.m.Lock()
+		products, excluded := filterProducts(response.Response.Rows)
+
+		s.m.Lock()
+		for _, product := range products {
 			s.Products[product.ID] = product
-			s.m.Unlock()
 		}
 
+		for _, e := range excluded {
+			s.Excluded[e.Product.ID] = e
+		}
+		s.m.Unlock()
+
 		logger.Log.WithFields(logrus.Fields{
 			"products": s.Products,
 		}).Logln(logrus.DebugLevel, "Отфильтровали товары из МойСклад")
@@ -171,6 +297,71 @@ func (s *MoySklad) GetProductsList(ctx context.Context) error {
 	return nil
 }
 
+// attributeName возвращает значение доп. поля. Для справочников МойСклад значением является объект,
+// у которого берем наименование элемента.
+func attributeName(value any) string {
+	if v, ok := value.(map[string]any); ok {
+		if name, ok := v["name"].(string); ok {
+			return name
+		}
+	}
+
+	return fmt.Sprintf("%v", value)
+}
+
+// GetStockByStore заполняет у товаров, в том числе исключенных из выгрузки, остатки в разрезе складов.
+func (s *MoySklad) GetStockByStore(ctx context.Context) error {
+	s.StoresLoaded = false
+	offset := 0
+	needQuery := true
+
+	for needQuery {
+		url := fmt.Sprintf("%sreport/stock/bystore?offset=%d", config.Config.MoySkladUrl, offset)
+		response := queryData[StockByStoreResponse](s, ctx, url)
+
+		if response.Error != nil {
+			return response.Error
+		}
+
+		needQuery = len(response.Response.Rows) >= response.Response.Meta.Limit && response.Response.Meta.Limit > 0
+		offset = offset + response.Response.Meta.Limit
+
+		s.m.Lock()
+		for _, row := range response.Response.Rows {
+			stores := make(map[string]float32, len(row.StockByStore))
+			for _, store := range row.StockByStore {
+				stores[store.Name] = store.Stock
+			}
+
+			id := entityId(row.Meta.Href)
+
+			if product, ok := s.Products[id]; ok {
+				product.Stores = stores
+				s.Products[id] = product
+			}
+
+			if excluded, ok := s.Excluded[id]; ok {
+				excluded.Product.Stores = stores
+				s.Excluded[id] = excluded
+			}
+		}
+		s.m.Unlock()
+	}
+
+	s.StoresLoaded = true
+
+	logger.Log.Logln(logrus.InfoLevel, "Получили остатки товаров по складам из МойСклад")
+
+	return nil
+}
+
+// entityId достает идентификатор сущности из ссылки МойСклад.
+func entityId(href string) string {
+	href, _, _ = strings.Cut(href, "?")
+
+	return href[strings.LastIndex(href, "/")+1:]
+}
+
 // GetImagesListProduct получает массив картинок товаров
 func (s *MoySklad) GetImagesListProduct(ctx context.Context, productId string, idImageWorker int) error {
 	url := fmt.Sprintf("%sentity/product/%s/images", config.Config.MoySkladUrl, productId)
@@ -257,14 +448,58 @@ func (s *MoySklad) getImage(ctx context.Context, imageRequest ImageRow, product
 
 func (s *MoySklad) Clear() {
 	s.Products = make(map[string]Product, 0)
+	s.Excluded = make(map[string]ExcludedProduct, 0)
+	s.StoresLoaded = false
 }
 
-func filterProducts(rows []Product) []Product {
-	rows = slices.DeleteFunc(rows, func(p Product) bool {
-		return p.ExportAvito == false || p.ImagesResponse.Meta.Size == 0 || p.Price == 0 || p.Stock == 0
-	})
+// AllProducts возвращает все товары: выгружаемые и исключенные из выгрузки.
+func (s *MoySklad) AllProducts() map[string]Product {
+	s.m.RLock()
+	defer s.m.RUnlock()
+
+	result := make(map[string]Product, len(s.Products)+len(s.Excluded))
+
+	for id, excluded := range s.Excluded {
+		result[id] = excluded.Product
+	}
+
+	for id, p := range s.Products {
+		result[id] = p
+	}
+
+	return result
+}
+
+// filterProducts отделяет товары для выгрузки от исключенных, запоминая причину исключения.
+func filterProducts(rows []Product) ([]Product, []ExcludedProduct) {
+	products := make([]Product, 0, len(rows))
+	excluded := make([]ExcludedProduct, 0)
+
+	for _, p := range rows {
+		if reason := excludeReason(p); reason != "" {
+			excluded = append(excluded, ExcludedProduct{Product: p, Reason: reason})
+			continue
+		}
+
+		products = append(products, p)
+	}
 
-	return rows
+	return products, excluded
+}
+
+// excludeReason возвращает причину, по которой товар не попадает в выгрузку, или пустую строку.
+// Цена проверяется выгрузками, так как у них может быть свой тип цены.
+func excludeReason(p Product) report.Reason {
+	switch {
+	case !p.ExportAvito:
+		return report.ReasonNoExportFlag
+	case p.Stock == 0:
+		return report.ReasonZeroStock
+	case p.ImagesResponse.Meta.Size == 0 && !config.Config.HasPlaceholderImage():
+		return report.ReasonNoImages
+	}
+
+	return ""
 }
 
 // queryData - запрос в API МойСклад
@@ -298,6 +533,35 @@ func queryData[T any](s *MoySklad, ctx context.Context, url string) APIServiceRe
 	return result
 }
 
+// sendData - запрос в API МойСклад с телом запроса (создание и изменение сущностей)
+func sendData[T any](s *MoySklad, ctx context.Context, method string, url string, body any) APIServiceResult[T] {
+	result := APIServiceResult[T]{}
+
+	contextWithTimeout, cancel := context.WithTimeout(ctx, time.Duration(60*time.Second))
+	defer cancel()
+
+	response, err := s.client.R().
+		SetHeader(`Authorization`, s.getAuthString()).
+		SetHeader(`Accept-Encoding`, `gzip`).
+		SetContext(contextWithTimeout).
+		SetBody(body).
+		SetResult(&result.Response).
+		Execute(method, url)
+
+	if err != nil {
+		result.Error = err
+		return result
+	}
+
+	if response.IsError() {
+		result.Error = fmt.Errorf("%s %s: %s", method, url, string(response.Body()))
+	}
+
+	result.Code = response.StatusCode()
+
+	return result
+}
+
 // getAuthString формирует строку для авторизации.
 func (s *MoySklad) getAuthString() string {
 	authStr := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s", config.Config.MoySkladLogin, config.Config.MoySkladPassword)))
@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"github.com/KirillKhitev/carat_export/internal/config"
+	"net/http"
+	"net/url"
+)
+
+// MetaRef ссылка на связанную сущность МойСклад.
+type MetaRef struct {
+	Meta Meta `json:"meta"`
+}
+
+// CustomerOrder заказ покупателя МойСклад.
+type CustomerOrder struct {
+	Id           string                  `json:"id,omitempty"`
+	Name         string                  `json:"name,omitempty"`
+	ExternalCode string                  `json:"externalCode,omitempty"`
+	Description  string                  `json:"description,omitempty"`
+	Organization MetaRef                 `json:"organization"`
+	Agent        MetaRef                 `json:"agent"`
+	Store        *MetaRef                `json:"store,omitempty"`
+	Positions    []CustomerOrderPosition `json:"positions,omitempty"`
+}
+
+// CustomerOrderPosition позиция заказа покупателя. Цена в копейках.
+type CustomerOrderPosition struct {
+	Quantity   float64 `json:"quantity"`
+	Price      float64 `json:"price"`
+	Reserve    float64 `json:"reserve,omitempty"`
+	Assortment MetaRef `json:"assortment"`
+}
+
+type CustomerOrderListResponse struct {
+	Meta MetaList        `json:"meta"`
+	Rows []CustomerOrder `json:"rows"`
+}
+
+// NewRef формирует ссылку на сущность МойСклад.
+func NewRef(entityType string, id string) MetaRef {
+	return MetaRef{Meta: EntityMeta(entityType, id)}
+}
+
+// FindCustomerOrder ищет заказ покупателя по внешнему коду.
+func (s *MoySklad) FindCustomerOrder(ctx context.Context, externalCode string) (*CustomerOrder, error) {
+	u := fmt.Sprintf("%sentity/customerorder?filter=%s", config.Config.MoySkladUrl, url.QueryEscape("externalCode="+externalCode))
+	response := queryData[CustomerOrderListResponse](s, ctx, u)
+
+	if response.Error != nil {
+		return nil, response.Error
+	}
+
+	if len(response.Response.Rows) == 0 {
+		return nil, nil
+	}
+
+	return &response.Response.Rows[0], nil
+}
+
+// CreateCustomerOrder создает заказ покупателя.
+func (s *MoySklad) CreateCustomerOrder(ctx context.Context, order CustomerOrder) (CustomerOrder, error) {
+	u := fmt.Sprintf("%sentity/customerorder", config.Config.MoySkladUrl)
+	response := sendData[CustomerOrder](s, ctx, http.MethodPost, u, order)
+
+	return response.Response, response.Error
+}
@@ -0,0 +1,162 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"github.com/KirillKhitev/carat_export/internal/config"
+	"github.com/KirillKhitev/carat_export/internal/logger"
+	"github.com/KirillKhitev/carat_export/internal/timeutil"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"time"
+)
+
+// DateTimeFormat формат даты и времени в API МойСклад. Время указывается московское.
+const DateTimeFormat = "2006-01-02 15:04:05.000"
+
+// defaultUpdateBatchSize сколько товаров изменять одним запросом.
+const defaultUpdateBatchSize = 100
+
+type Meta struct {
+	Href      string `json:"href"`
+	Type      string `json:"type"`
+	MediaType string `json:"mediaType"`
+}
+
+// AttributeMetadata описание доп. поля сущности МойСклад.
+type AttributeMetadata struct {
+	Meta Meta   `json:"meta"`
+	Id   string `json:"id"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+type AttributeMetadataListResponse struct {
+	Meta MetaList            `json:"meta"`
+	Rows []AttributeMetadata `json:"rows"`
+}
+
+// ProductAttributesUpdate новые значения доп. полей товара, по названию доп. поля.
+type ProductAttributesUpdate struct {
+	ProductID string
+	Values    map[string]any
+}
+
+type attributeValue struct {
+	Meta  Meta `json:"meta"`
+	Value any  `json:"value"`
+}
+
+type productUpdate struct {
+	Meta       Meta             `json:"meta"`
+	Attributes []attributeValue `json:"attributes"`
+}
+
+// GetProductAttributesMetadata получает описания доп. полей товаров по их названиям.
+func (s *MoySklad) GetProductAttributesMetadata(ctx context.Context) (map[string]AttributeMetadata, error) {
+	url := fmt.Sprintf("%sentity/product/metadata/attributes", config.Config.MoySkladUrl)
+	response := queryData[AttributeMetadataListResponse](s, ctx, url)
+
+	if response.Error != nil {
+		return nil, response.Error
+	}
+
+	result := make(map[string]AttributeMetadata, len(response.Response.Rows))
+	for _, a := range response.Response.Rows {
+		result[a.Name] = a
+	}
+
+	return result, nil
+}
+
+// UpdateProductsAttributes записывает значения доп. полей товаров пакетами через entity/product.
+// Доп. поля, которых нет в МойСклад, пропускаются.
+func (s *MoySklad) UpdateProductsAttributes(ctx context.Context, updates []ProductAttributesUpdate) error {
+	metadata, err := s.GetProductAttributesMetadata(ctx)
+	if err != nil {
+		return err
+	}
+
+	batchSize := config.Config.WriteBack.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultUpdateBatchSize
+	}
+
+	batch := make([]productUpdate, 0, batchSize)
+	missing := make(map[string]struct{})
+
+	for _, update := range updates {
+		item := productUpdate{
+			Meta:       productMeta(update.ProductID),
+			Attributes: make([]attributeValue, 0, len(update.Values)),
+		}
+
+		for name, value := range update.Values {
+			attr, ok := metadata[name]
+			if !ok {
+				missing[name] = struct{}{}
+				continue
+			}
+
+			if t, ok := value.(time.Time); ok {
+				value = t.In(timeutil.Moscow).Format(DateTimeFormat)
+			}
+
+			item.Attributes = append(item.Attributes, attributeValue{Meta: attr.Meta, Value: value})
+		}
+
+		if len(item.Attributes) == 0 {
+			continue
+		}
+
+		batch = append(batch, item)
+
+		if len(batch) == batchSize {
+			if err := s.updateProducts(ctx, batch); err != nil {
+				return err
+			}
+
+			batch = batch[:0]
+		}
+	}
+
+	if len(missing) > 0 {
+		logger.Log.WithFields(logrus.Fields{
+			"attributes": missing,
+		}).Log(logrus.WarnLevel, "В МойСклад нет доп. полей товаров для записи данных Avito")
+	}
+
+	if len(batch) > 0 {
+		return s.updateProducts(ctx, batch)
+	}
+
+	return nil
+}
+
+// updateProducts отправляет пакет изменений товаров.
+func (s *MoySklad) updateProducts(ctx context.Context, batch []productUpdate) error {
+	url := fmt.Sprintf("%sentity/product", config.Config.MoySkladUrl)
+	response := sendData[[]map[string]any](s, ctx, http.MethodPost, url, batch)
+
+	if response.Error != nil {
+		return response.Error
+	}
+
+	logger.Log.Logf(logrus.InfoLevel, "Обновили доп. поля товаров в МойСклад: %d", len(batch))
+
+	return nil
+}
+
+// productMeta формирует ссылку на товар МойСклад.
+func productMeta(id string) Meta {
+	return EntityMeta("product", id)
+}
+
+// EntityMeta формирует ссылку на сущность МойСклад по ее типу и идентификатору.
+func EntityMeta(entityType string, id string) Meta {
+	return Meta{
+		Href:      fmt.Sprintf("%sentity/%s/%s", config.Config.MoySkladUrl, entityType, id),
+		Type:      entityType,
+		MediaType: "application/json",
+	}
+}
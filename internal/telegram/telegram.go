@@ -0,0 +1,224 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"github.com/KirillKhitev/carat_export/internal/config"
+	"github.com/KirillKhitev/carat_export/internal/history"
+	"github.com/KirillKhitev/carat_export/internal/logger"
+	"github.com/KirillKhitev/carat_export/internal/snapshot"
+	"github.com/KirillKhitev/carat_export/internal/telegramapi"
+	"github.com/sirupsen/logrus"
+	"html/template"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// LedgerName журнал опубликованных товаров: ID товара МойСклад -> ID первого сообщения поста.
+// Чтобы опубликовать товар повторно, его достаточно удалить из журнала.
+const LedgerName = "telegram_announced"
+
+// seedValue значение записей журнала, добавленных без публикации при первом запуске.
+const seedValue = "seed"
+
+// postInterval пауза между постами, чтобы не упереться в ограничения Telegram на частоту сообщений.
+const postInterval = 3 * time.Second
+
+// defaultTemplate подпись к посту по умолчанию. Шаблон формирует HTML разметку Telegram.
+const defaultTemplate = `<b>{{.Name}}</b>
+{{with .Folder}}{{.}}
+{{end}}
+Цена: {{.Price}} ₽{{with .AvitoURL}}
+
+<a href="{{.}}">Купить на Avito</a>{{end}}`
+
+// Publisher публикует в канале Telegram товары, которые впервые попали в выгрузку.
+type Publisher struct {
+	api  *telegramapi.Client
+	tmpl *template.Template
+}
+
+// NewPublisher создает публикатор с шаблоном подписи из настроек.
+func NewPublisher() (*Publisher, error) {
+	tmpl, err := loadTemplate(config.Config.Telegram.Template)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Publisher{
+		api:  telegramapi.NewClient(),
+		tmpl: tmpl,
+	}, nil
+}
+
+// Enabled показывает, настроена ли публикация в Telegram.
+func Enabled() bool {
+	return config.Config.Telegram.Enabled()
+}
+
+// Publish публикует товары снимка, которых нет в журнале опубликованных. При первом запуске
+// текущие товары заносятся в журнал без публикации, чтобы не публиковать весь каталог. За один запуск публикуется не больше MaxPosts товаров, остальные - в следующих запусках.
+func (p *Publisher) Publish(ctx context.Context, s snapshot.Snapshot) error {
+	ledger, err := history.LoadLedger(LedgerName)
+	if err != nil {
+		return err
+	}
+
+	if !ledger.Seeded() {
+		for _, product := range s.Products {
+			ledger.Add(product.ID, seedValue)
+		}
+
+		ledger.MarkSeeded()
+
+		logger.Log.Logf(logrus.InfoLevel, "Журнал публикаций Telegram заполнен текущими товарами: %d", len(s.Products))
+
+		return ledger.Save()
+	}
+
+	posted := 0
+
+	for _, product := range s.Products {
+		if ledger.Has(product.ID) {
+			continue
+		}
+
+		if posted >= config.Config.Telegram.MaxPosts {
+			break
+		}
+
+		if posted > 0 {
+			select {
+			case <-ctx.Done():
+				return ledger.Save()
+			case <-time.After(postInterval):
+			}
+		}
+
+		messageId, err := p.post(ctx, product)
+		if err != nil {
+			logger.Log.WithFields(logrus.Fields{
+				"error":     err,
+				"productId": product.ID,
+			}).Log(logrus.ErrorLevel, "Ошибка при публикации товара в Telegram")
+
+			continue
+		}
+
+		ledger.Add(product.ID, strconv.FormatInt(messageId, 10))
+
+		if err := ledger.Save(); err != nil {
+			return err
+		}
+
+		posted++
+	}
+
+	if posted > 0 {
+		logger.Log.Logf(logrus.InfoLevel, "Опубликовано новых товаров в Telegram: %d", posted)
+	}
+
+	return nil
+}
+
+// post отправляет пост о товаре: альбом из изображений, одно фото или текст, если изображений нет.
+// Если подпись не помещается под фото, она отправляется отдельным сообщением перед фото.
+// Возвращает ID первого сообщения.
+func (p *Publisher) post(ctx context.Context, product snapshot.Product) (int64, error) {
+	caption, err := p.Caption(product)
+	if err != nil {
+		return 0, err
+	}
+
+	chatId := config.Config.Telegram.ChatId
+
+	if len(product.Images) == 0 || utf8.RuneCountInString(caption) > telegramapi.MaxCaptionLength {
+		message, err := p.api.SendMessage(ctx, chatId, caption)
+		if err != nil || len(product.Images) == 0 {
+			return message.MessageId, err
+		}
+
+		// Текст уже опубликован: при ошибке с фото пост не повторяется, чтобы не дублировать текст.
+		if _, err := p.sendImages(ctx, chatId, product.Images, ""); err != nil {
+			logger.Log.WithFields(logrus.Fields{
+				"error":     err,
+				"productId": product.ID,
+			}).Log(logrus.WarnLevel, "Не удалось отправить фото товара в Telegram")
+		}
+
+		return message.MessageId, nil
+	}
+
+	return p.sendImages(ctx, chatId, product.Images, caption)
+}
+
+// sendImages отправляет изображения одним фото или альбомом с подписью у первого фото.
+// Возвращает ID первого сообщения.
+func (p *Publisher) sendImages(ctx context.Context, chatId string, images []string, caption string) (int64, error) {
+	if len(images) == 1 {
+		message, err := p.api.SendPhoto(ctx, chatId, images[0], caption)
+		return message.MessageId, err
+	}
+
+	media := make([]telegramapi.InputMedia, 0, telegramapi.MaxMediaGroup)
+
+	for i, image := range images {
+		if i == telegramapi.MaxMediaGroup {
+			break
+		}
+
+		item := telegramapi.InputMedia{Type: "photo", Media: image}
+		if i == 0 && caption != "" {
+			item.Caption = caption
+			item.ParseMode = telegramapi.ParseModeHTML
+		}
+
+		media = append(media, item)
+	}
+
+	messages, err := p.api.SendMediaGroup(ctx, chatId, media)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(messages) == 0 {
+		return 0, nil
+	}
+
+	return messages[0].MessageId, nil
+}
+
+// Caption формирует подпись к посту о товаре по шаблону.
+func (p *Publisher) Caption(product snapshot.Product) (string, error) {
+	var sb strings.Builder
+
+	if err := p.tmpl.Execute(&sb, product); err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(sb.String()), nil
+}
+
+// loadTemplate разбирает шаблон подписи из файла path, пустой path - шаблон по умолчанию.
+func loadTemplate(path string) (*template.Template, error) {
+	text := defaultTemplate
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("не удалось прочитать шаблон поста %s: %w", path, err)
+		}
+
+		text = string(data)
+	}
+
+	tmpl, err := template.New("telegram").Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось разобрать шаблон поста %s: %w", path, err)
+	}
+
+	return tmpl, nil
+}
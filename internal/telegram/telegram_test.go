@@ -0,0 +1,214 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/KirillKhitev/carat_export/internal/config"
+	"github.com/KirillKhitev/carat_export/internal/history"
+	"github.com/KirillKhitev/carat_export/internal/snapshot"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// call вызов метода Bot API, полученный заглушкой.
+type call struct {
+	Method  string
+	Text    string `json:"text"`
+	Caption string `json:"caption"`
+}
+
+// bot заглушка Bot API: запоминает вызовы и отвечает сообщениями с растущими ID.
+type bot struct {
+	m     sync.Mutex
+	calls []call
+	// fail метод, который возвращает ошибку.
+	fail string
+}
+
+func (b *bot) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	c := call{Method: path.Base(r.URL.Path)}
+	json.NewDecoder(r.Body).Decode(&c)
+	b.calls = append(b.calls, c)
+
+	if c.Method == b.fail {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"ok":false,"error_code":400,"description":"Bad Request: wrong file identifier/HTTP URL specified"}`)
+		return
+	}
+
+	fmt.Fprintf(w, `{"ok":true,"result":{"message_id":%d}}`, 100+len(b.calls))
+}
+
+// newBot настраивает публикацию на заглушку Bot API с пустым журналом.
+func newBot(t *testing.T) (*bot, *Publisher) {
+	t.Helper()
+
+	b := &bot{}
+
+	srv := httptest.NewServer(b)
+	t.Cleanup(srv.Close)
+
+	old := config.Config
+	t.Cleanup(func() { config.Config = old })
+
+	config.Config.HistoryDir = t.TempDir()
+	config.Config.Telegram = config.TelegramParams{
+		ApiUrl:   srv.URL,
+		BotToken: "token",
+		ChatId:   "@channel",
+		MaxPosts: 10,
+	}
+
+	publisher, err := NewPublisher()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return b, publisher
+}
+
+// seed заносит товары в журнал опубликованных.
+func seed(t *testing.T, ids ...string) {
+	t.Helper()
+
+	ledger, err := history.LoadLedger(LedgerName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range ids {
+		ledger.Add(id, seedValue)
+	}
+
+	if err := ledger.Save(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func catalog(products ...snapshot.Product) snapshot.Snapshot {
+	return snapshot.Snapshot{Products: products}
+}
+
+func coin(id string) snapshot.Product {
+	return snapshot.Product{ID: id, Name: "Монета " + id, Price: 1500, Images: []string{"https://example.com/" + id + ".jpg"}}
+}
+
+func ledgerValue(t *testing.T, id string) (string, bool) {
+	t.Helper()
+
+	ledger, err := history.LoadLedger(LedgerName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entry, ok := ledger.Get(id)
+
+	return entry.Value, ok
+}
+
+func TestFirstRunSeedsLedger(t *testing.T) {
+	b, publisher := newBot(t)
+
+	if err := publisher.Publish(context.Background(), catalog(coin("1"), coin("2"))); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(b.calls) != 0 {
+		t.Fatalf("calls = %+v, want none on first run", b.calls)
+	}
+
+	for _, id := range []string{"1", "2"} {
+		if value, ok := ledgerValue(t, id); !ok || value != seedValue {
+			t.Errorf("ledger[%s] = %q, want %q", id, value, seedValue)
+		}
+	}
+}
+
+func TestFirstProductAfterEmptyCatalogIsPosted(t *testing.T) {
+	b, publisher := newBot(t)
+
+	for i := 0; i < 2; i++ {
+		if err := publisher.Publish(context.Background(), catalog()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := publisher.Publish(context.Background(), catalog(coin("1"))); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(b.calls) != 1 {
+		t.Fatalf("calls = %+v, want first product posted", b.calls)
+	}
+}
+
+func TestLedgerPreventsReposts(t *testing.T) {
+	b, publisher := newBot(t)
+	seed(t, "1")
+
+	for i := 0; i < 2; i++ {
+		if err := publisher.Publish(context.Background(), catalog(coin("1"), coin("2"))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(b.calls) != 1 || b.calls[0].Method != "sendPhoto" || !strings.Contains(b.calls[0].Caption, "Монета 2") {
+		t.Fatalf("calls = %+v, want one sendPhoto for product 2", b.calls)
+	}
+
+	if value, _ := ledgerValue(t, "2"); value != "101" {
+		t.Fatalf("ledger[2] = %q, want message id 101", value)
+	}
+}
+
+func TestLongCaptionIsSentAsMessage(t *testing.T) {
+	b, publisher := newBot(t)
+	seed(t, "1")
+
+	p := coin("2")
+	p.Name = strings.Repeat("Ж", 1100)
+
+	if err := publisher.Publish(context.Background(), catalog(p)); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(b.calls) != 2 || b.calls[0].Method != "sendMessage" || b.calls[1].Method != "sendPhoto" || b.calls[1].Caption != "" {
+		t.Fatalf("calls = %+v, want sendMessage and sendPhoto without caption", b.calls)
+	}
+
+	if value, _ := ledgerValue(t, "2"); value != "101" {
+		t.Fatalf("ledger[2] = %q, want text message id 101", value)
+	}
+}
+
+func TestFailedPostIsNotRecorded(t *testing.T) {
+	b, publisher := newBot(t)
+	seed(t, "1")
+	b.fail = "sendPhoto"
+
+	if err := publisher.Publish(context.Background(), catalog(coin("2"))); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := ledgerValue(t, "2"); ok {
+		t.Fatal("failed post is recorded in ledger")
+	}
+
+	b.fail = ""
+
+	if err := publisher.Publish(context.Background(), catalog(coin("2"))); err != nil {
+		t.Fatal(err)
+	}
+
+	if value, ok := ledgerValue(t, "2"); !ok || value != "102" {
+		t.Fatalf("ledger[2] = %q, want retried post message id 102", value)
+	}
+}
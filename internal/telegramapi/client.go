@@ -0,0 +1,149 @@
+package telegramapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/KirillKhitev/carat_export/internal/config"
+	"github.com/go-resty/resty/v2"
+	"strings"
+	"time"
+)
+
+// DefaultURL адрес Bot API Telegram.
+const DefaultURL = "https://api.telegram.org/"
+
+// ParseModeHTML разметка текста сообщений в HTML.
+const ParseModeHTML = "HTML"
+
+// MaxCaptionLength максимальная длина подписи к фото.
+const MaxCaptionLength = 1024
+
+// MaxMediaGroup максимальное количество фото в альбоме.
+const MaxMediaGroup = 10
+
+// Client клиент Bot API Telegram.
+type Client struct {
+	client *resty.Client
+	token  string
+}
+
+// NewClient создает клиент с токеном бота из настроек.
+func NewClient() *Client {
+	return &Client{
+		client: resty.New(),
+		token:  config.Config.Telegram.BotToken,
+	}
+}
+
+// APIError ошибка, которую возвращает Bot API.
+type APIError struct {
+	Code        int    `json:"error_code"`
+	Description string `json:"description"`
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%d: %s", e.Code, e.Description)
+}
+
+type response struct {
+	Ok     bool            `json:"ok"`
+	Result json.RawMessage `json:"result"`
+	APIError
+}
+
+// Message отправленное сообщение.
+type Message struct {
+	MessageId int64 `json:"message_id"`
+}
+
+// InputMedia фото альбома. Подпись альбома задается у первого фото.
+type InputMedia struct {
+	Type      string `json:"type"`
+	Media     string `json:"media"`
+	Caption   string `json:"caption,omitempty"`
+	ParseMode string `json:"parse_mode,omitempty"`
+}
+
+// methodURL формирует адрес метода API.
+func (c *Client) methodURL(method string) string {
+	base := config.Config.Telegram.ApiUrl
+	if base == "" {
+		base = DefaultURL
+	}
+
+	return strings.TrimSuffix(base, "/") + "/bot" + c.token + "/" + method
+}
+
+// call вызывает метод API и разбирает поле result ответа в result.
+func (c *Client) call(ctx context.Context, method string, body any, result any) error {
+	contextWithTimeout, cancel := context.WithTimeout(ctx, time.Duration(60*time.Second))
+	defer cancel()
+
+	var data response
+
+	resp, err := c.client.R().
+		SetContext(contextWithTimeout).
+		ForceContentType("application/json").
+		SetBody(body).
+		SetResult(&data).
+		SetError(&data).
+		Post(c.methodURL(method))
+
+	if err != nil {
+		return err
+	}
+
+	if !data.Ok && data.Description == "" {
+		return fmt.Errorf("%s: %s", method, resp.Status())
+	}
+
+	if !data.Ok {
+		return fmt.Errorf("%s: %w", method, &data.APIError)
+	}
+
+	if result == nil {
+		return nil
+	}
+
+	return json.Unmarshal(data.Result, result)
+}
+
+// SendMessage отправляет текстовое сообщение.
+func (c *Client) SendMessage(ctx context.Context, chatId string, text string) (Message, error) {
+	var result Message
+
+	err := c.call(ctx, "sendMessage", map[string]any{
+		"chat_id":    chatId,
+		"text":       text,
+		"parse_mode": ParseModeHTML,
+	}, &result)
+
+	return result, err
+}
+
+// SendPhoto отправляет одно фото с подписью. photo - адрес изображения.
+func (c *Client) SendPhoto(ctx context.Context, chatId string, photo string, caption string) (Message, error) {
+	var result Message
+
+	err := c.call(ctx, "sendPhoto", map[string]any{
+		"chat_id":    chatId,
+		"photo":      photo,
+		"caption":    caption,
+		"parse_mode": ParseModeHTML,
+	}, &result)
+
+	return result, err
+}
+
+// SendMediaGroup отправляет альбом из 2-10 фото.
+func (c *Client) SendMediaGroup(ctx context.Context, chatId string, media []InputMedia) ([]Message, error) {
+	var result []Message
+
+	err := c.call(ctx, "sendMediaGroup", map[string]any{
+		"chat_id": chatId,
+		"media":   media,
+	}, &result)
+
+	return result, err
+}
@@ -0,0 +1,24 @@
+package textutil
+
+import "strings"
+
+// FirstNotEmpty возвращает первое непустое значение.
+func FirstNotEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+
+	return ""
+}
+
+// Truncate обрезает строку до limit символов.
+func Truncate(s string, limit int) string {
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return s
+	}
+
+	return strings.TrimSpace(string(runes[:limit]))
+}
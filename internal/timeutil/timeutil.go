@@ -0,0 +1,57 @@
+package timeutil
+
+import (
+	"fmt"
+	"time"
+)
+
+// Moscow часовой пояс, в котором работают МойСклад и Avito, независимо от пояса сервера.
+var Moscow = loadMoscow()
+
+// loadMoscow загружает пояс Europe/Moscow, а если на сервере нет базы часовых поясов,
+// использует фиксированное смещение UTC+3.
+func loadMoscow() *time.Location {
+	if loc, err := time.LoadLocation("Europe/Moscow"); err == nil {
+		return loc
+	}
+
+	return time.FixedZone("MSK", 3*60*60)
+}
+
+// Season сезонный период публикации, задается датами вида "MM-DD" без года.
+type Season struct {
+	From string
+	To   string
+}
+
+// Window возвращает текущий или ближайший будущий период сезона относительно now.
+// Период может переходить через новый год, например с "12-01" по "01-15".
+// Конец периода - последняя секунда дня To по московскому времени.
+func (s Season) Window(now time.Time) (time.Time, time.Time, error) {
+	now = now.In(Moscow)
+
+	from, err := time.ParseInLocation("01-02", s.From, Moscow)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("неверная дата начала сезона %q: %w", s.From, err)
+	}
+
+	to, err := time.ParseInLocation("01-02", s.To, Moscow)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("неверная дата окончания сезона %q: %w", s.To, err)
+	}
+
+	for _, year := range []int{now.Year() - 1, now.Year(), now.Year() + 1} {
+		begin := time.Date(year, from.Month(), from.Day(), 0, 0, 0, 0, Moscow)
+		end := time.Date(year, to.Month(), to.Day(), 23, 59, 59, 0, Moscow)
+
+		if end.Before(begin) {
+			end = end.AddDate(1, 0, 0)
+		}
+
+		if !now.After(end) {
+			return begin, end, nil
+		}
+	}
+
+	return time.Time{}, time.Time{}, fmt.Errorf("не удалось определить период сезона %s - %s", s.From, s.To)
+}
@@ -0,0 +1,106 @@
+package timeutil
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSeasonWindow(t *testing.T) {
+	msk := func(year int, month time.Month, day, hour, min, sec int) time.Time {
+		return time.Date(year, month, day, hour, min, sec, 0, Moscow)
+	}
+
+	tests := []struct {
+		name      string
+		season    Season
+		now       time.Time
+		wantBegin time.Time
+		wantEnd   time.Time
+	}{
+		{
+			name:      "inside season",
+			season:    Season{From: "06-01", To: "08-31"},
+			now:       msk(2024, time.July, 15, 12, 0, 0),
+			wantBegin: msk(2024, time.June, 1, 0, 0, 0),
+			wantEnd:   msk(2024, time.August, 31, 23, 59, 59),
+		},
+		{
+			name:      "before season",
+			season:    Season{From: "06-01", To: "08-31"},
+			now:       msk(2024, time.March, 1, 12, 0, 0),
+			wantBegin: msk(2024, time.June, 1, 0, 0, 0),
+			wantEnd:   msk(2024, time.August, 31, 23, 59, 59),
+		},
+		{
+			name:      "after season is next year",
+			season:    Season{From: "06-01", To: "08-31"},
+			now:       msk(2024, time.September, 1, 0, 0, 0),
+			wantBegin: msk(2025, time.June, 1, 0, 0, 0),
+			wantEnd:   msk(2025, time.August, 31, 23, 59, 59),
+		},
+		{
+			name:      "last second of season",
+			season:    Season{From: "06-01", To: "08-31"},
+			now:       msk(2024, time.August, 31, 23, 59, 59),
+			wantBegin: msk(2024, time.June, 1, 0, 0, 0),
+			wantEnd:   msk(2024, time.August, 31, 23, 59, 59),
+		},
+		{
+			name:      "season across new year in january",
+			season:    Season{From: "12-01", To: "01-15"},
+			now:       msk(2025, time.January, 10, 12, 0, 0),
+			wantBegin: msk(2024, time.December, 1, 0, 0, 0),
+			wantEnd:   msk(2025, time.January, 15, 23, 59, 59),
+		},
+		{
+			name:      "season across new year in december",
+			season:    Season{From: "12-01", To: "01-15"},
+			now:       msk(2024, time.December, 20, 12, 0, 0),
+			wantBegin: msk(2024, time.December, 1, 0, 0, 0),
+			wantEnd:   msk(2025, time.January, 15, 23, 59, 59),
+		},
+		{
+			name:      "season across new year after end",
+			season:    Season{From: "12-01", To: "01-15"},
+			now:       msk(2025, time.February, 1, 12, 0, 0),
+			wantBegin: msk(2025, time.December, 1, 0, 0, 0),
+			wantEnd:   msk(2026, time.January, 15, 23, 59, 59),
+		},
+		{
+			// 31 августа 22:30 UTC - уже 1 сентября в Москве, сезон закончился.
+			name:      "now is converted to moscow time",
+			season:    Season{From: "06-01", To: "08-31"},
+			now:       time.Date(2024, time.August, 31, 22, 30, 0, 0, time.UTC),
+			wantBegin: msk(2025, time.June, 1, 0, 0, 0),
+			wantEnd:   msk(2025, time.August, 31, 23, 59, 59),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			begin, end, err := tt.season.Window(tt.now)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !begin.Equal(tt.wantBegin) || !end.Equal(tt.wantEnd) {
+				t.Fatalf("window = %s - %s, want %s - %s", begin, end, tt.wantBegin, tt.wantEnd)
+			}
+		})
+	}
+}
+
+func TestSeasonWindowErrors(t *testing.T) {
+	for _, season := range []Season{{From: "", To: "08-31"}, {From: "06-01", To: "31-08"}, {From: "13-01", To: "12-31"}} {
+		if _, _, err := season.Window(time.Now()); err == nil {
+			t.Errorf("season %+v: expected error", season)
+		}
+	}
+}
+
+func TestMoscowOffset(t *testing.T) {
+	_, offset := time.Date(2024, time.January, 1, 0, 0, 0, 0, Moscow).Zone()
+	if offset != 3*60*60 {
+		t.Fatalf("offset = %d, want UTC+3", offset)
+	}
+}
@@ -0,0 +1,414 @@
+package vk
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/KirillKhitev/carat_export/internal/config"
+	"github.com/KirillKhitev/carat_export/internal/description"
+	"github.com/KirillKhitev/carat_export/internal/history"
+	"github.com/KirillKhitev/carat_export/internal/images"
+	"github.com/KirillKhitev/carat_export/internal/logger"
+	"github.com/KirillKhitev/carat_export/internal/report"
+	"github.com/KirillKhitev/carat_export/internal/storage"
+	"github.com/KirillKhitev/carat_export/internal/textutil"
+	"github.com/KirillKhitev/carat_export/internal/vkapi"
+	"github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Журналы синхронизации с VK Market.
+const (
+	// ItemsLedgerName ID товара МойСклад -> "ID товара VK/ID подборки".
+	ItemsLedgerName = "vk_items"
+	// HashesLedgerName ID товара МойСклад -> хеш последних отправленных данных товара.
+	HashesLedgerName = "vk_item_hashes"
+	// PhotosLedgerName адрес изображения -> ID загруженной фотографии VK.
+	PhotosLedgerName = "vk_photos"
+	// AlbumsLedgerName группа МойСклад -> ID подборки VK, созданной выгрузкой.
+	AlbumsLedgerName = "vk_albums"
+)
+
+// Ограничения VK Market.
+const (
+	maxNameLength        = 100
+	minDescriptionLength = 10
+	maxAlbumTitle        = 128
+	maxPhotos            = 5
+)
+
+// Exporter синхронизирует товары и подборки VK Market с товарами МойСклад.
+type Exporter struct {
+	api    *vkapi.Client
+	items  *history.Ledger
+	hashes *history.Ledger
+	photos *history.Ledger
+	albums *history.Ledger
+	// albumIds подборки сообщества после синхронизации подборок.
+	albumIds map[int64]bool
+}
+
+func NewExporter() *Exporter {
+	return &Exporter{
+		api: vkapi.NewClient(),
+	}
+}
+
+// Enabled показывает, настроена ли синхронизация с VK.
+func Enabled() bool {
+	return config.Config.VK.Enabled()
+}
+
+// Sync приводит подборки и товары сообщества в соответствие с выгружаемыми товарами:
+// создает новые, изменяет изменившиеся и удаляет товары, которых больше нет в выгрузке.
+// Возвращает результат по каждому товару: ID товара -> причина.
+func (e *Exporter) Sync(ctx context.Context, products map[string]storage.Product) (map[string]report.Reason, error) {
+	ledgers := map[string]**history.Ledger{
+		ItemsLedgerName:  &e.items,
+		HashesLedgerName: &e.hashes,
+		PhotosLedgerName: &e.photos,
+		AlbumsLedgerName: &e.albums,
+	}
+
+	for name, ledger := range ledgers {
+		l, err := history.LoadLedger(name)
+		if err != nil {
+			return nil, err
+		}
+
+		*ledger = l
+	}
+
+	albums, err := e.syncAlbums(ctx, products)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(products))
+	for id := range products {
+		ids = append(ids, id)
+	}
+
+	sort.Strings(ids)
+
+	renderer := description.NewRenderer()
+	reasons := make(map[string]report.Reason, len(products))
+
+	for _, id := range ids {
+		p := products[id]
+
+		reason, err := e.syncItem(ctx, p, albums[p.Folder], renderer)
+		if err != nil {
+			reason = report.ReasonValidationErr
+
+			logger.Log.WithFields(logrus.Fields{
+				"error":     err,
+				"productId": p.ID,
+			}).Log(logrus.ErrorLevel, "Ошибка при выгрузке товара в VK")
+		}
+
+		reasons[p.ID] = reason
+	}
+
+	e.deleteRemovedItems(ctx, products)
+
+	for _, ledger := range ledgers {
+		if err := (*ledger).Save(); err != nil {
+			return nil, err
+		}
+	}
+
+	return reasons, nil
+}
+
+// syncAlbums создает подборки для групп товаров и удаляет созданные выгрузкой подборки групп,
+// товаров которых больше нет. Возвращает группу -> ID подборки.
+func (e *Exporter) syncAlbums(ctx context.Context, products map[string]storage.Product) (map[string]int64, error) {
+	existing, err := e.api.Albums(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	exists := make(map[int64]bool, len(existing))
+	for _, album := range existing {
+		exists[album.Id] = true
+	}
+
+	result := make(map[string]int64)
+	e.albumIds = exists
+
+	for _, p := range products {
+		if _, ok := result[p.Folder]; ok || p.Folder == "" {
+			continue
+		}
+
+		if entry, ok := e.albums.Get(p.Folder); ok {
+			if id, err := strconv.ParseInt(entry.Value, 10, 64); err == nil && exists[id] {
+				result[p.Folder] = id
+				continue
+			}
+		}
+
+		id, err := e.api.AddAlbum(ctx, textutil.Truncate(p.Folder, maxAlbumTitle))
+		if err != nil {
+			return nil, err
+		}
+
+		e.albums.Add(p.Folder, strconv.FormatInt(id, 10))
+		result[p.Folder] = id
+		exists[id] = true
+
+		logger.Log.Logf(logrus.InfoLevel, "Создана подборка VK «%s»", p.Folder)
+	}
+
+	for folder, entry := range e.albums.Entries {
+		if _, ok := result[folder]; ok {
+			continue
+		}
+
+		id, _ := strconv.ParseInt(entry.Value, 10, 64)
+
+		if exists[id] {
+			if err := e.api.DeleteAlbum(ctx, id); err != nil {
+				return nil, err
+			}
+
+			delete(exists, id)
+		}
+
+		e.albums.Delete(folder)
+	}
+
+	return result, nil
+}
+
+// syncItem создает или изменяет товар VK, если его данные изменились с прошлой выгрузки.
+// Возвращает причину, по которой товар выгружен или не выгружен.
+func (e *Exporter) syncItem(ctx context.Context, p storage.Product, albumId int64, renderer *description.Renderer) (report.Reason, error) {
+	imagesParams := config.Config.Images
+	imagesParams.Max = maxPhotos
+	productImages := images.Prepare(p, imagesParams)
+	price := p.PriceByType(config.Config.VK.PriceType)
+
+	if len(productImages) == 0 || price <= 0 {
+		reason := report.ReasonNoPrice
+		if len(productImages) == 0 {
+			reason = report.ReasonNoImages
+		}
+
+		// VK не принимает товары без фото и цены: ранее выгруженный товар удаляется.
+		return reason, e.deleteItem(ctx, p.ID)
+	}
+
+	text, err := renderer.Render(p)
+	if err != nil {
+		text = renderer.RenderDefault(p)
+	}
+
+	text = description.ToText(text)
+	if utf8.RuneCountInString(text) < minDescriptionLength {
+		text = strings.TrimSpace(p.Name + "\n" + text)
+	}
+
+	item := vkapi.Item{
+		Name:        textutil.Truncate(p.Name, maxNameLength),
+		Description: text,
+		CategoryId:  config.Config.VK.CategoryId,
+		Price:       price,
+		Sku:         p.Article,
+	}
+
+	hash, err := itemHash(item, productImages, albumId)
+	if err != nil {
+		return "", err
+	}
+
+	itemId, oldAlbumId := e.mapping(p.ID)
+	if !e.albumIds[oldAlbumId] {
+		// Подборка уже удалена, убирать из нее товар не нужно.
+		oldAlbumId = 0
+	}
+
+	if entry, ok := e.hashes.Get(p.ID); ok && itemId > 0 && entry.Value == hash {
+		return report.ReasonExported, nil
+	}
+
+	for i, img := range productImages {
+		photoId, err := e.photo(ctx, img, i == 0)
+		if err != nil {
+			return "", err
+		}
+
+		if i == 0 {
+			item.MainPhotoId = photoId
+		} else {
+			item.PhotoIds = append(item.PhotoIds, photoId)
+		}
+	}
+
+	if itemId > 0 {
+		if err := e.api.EditItem(ctx, itemId, item); err != nil {
+			return "", err
+		}
+	} else {
+		if itemId, err = e.api.AddItem(ctx, item); err != nil {
+			return "", err
+		}
+
+		// Созданный товар сразу записывается в журнал, чтобы при ошибке с подборкой
+		// следующий запуск изменил его, а не создал повторно.
+		oldAlbumId = 0
+		e.items.Add(p.ID, fmt.Sprintf("%d/%d", itemId, oldAlbumId))
+	}
+
+	if oldAlbumId != albumId {
+		if oldAlbumId > 0 {
+			if err := e.api.RemoveFromAlbum(ctx, itemId, oldAlbumId); err != nil {
+				return "", err
+			}
+
+			e.items.Add(p.ID, fmt.Sprintf("%d/%d", itemId, 0))
+		}
+
+		if albumId > 0 {
+			if err := e.api.AddToAlbum(ctx, itemId, albumId); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	e.items.Add(p.ID, fmt.Sprintf("%d/%d", itemId, albumId))
+	e.hashes.Add(p.ID, hash)
+
+	return report.ReasonExported, nil
+}
+
+// deleteRemovedItems удаляет товары VK, которых больше нет в выгрузке.
+func (e *Exporter) deleteRemovedItems(ctx context.Context, products map[string]storage.Product) {
+	for id := range e.items.Entries {
+		if _, ok := products[id]; ok {
+			continue
+		}
+
+		if err := e.deleteItem(ctx, id); err != nil {
+			logger.Log.WithFields(logrus.Fields{
+				"error":     err,
+				"productId": id,
+			}).Log(logrus.ErrorLevel, "Ошибка при удалении товара из VK")
+		}
+	}
+}
+
+// deleteItem удаляет товар VK, выгруженный для товара МойСклад, если он есть.
+func (e *Exporter) deleteItem(ctx context.Context, productId string) error {
+	itemId, _ := e.mapping(productId)
+	if itemId == 0 {
+		return nil
+	}
+
+	if err := e.api.DeleteItem(ctx, itemId); err != nil {
+		return err
+	}
+
+	e.items.Delete(productId)
+	e.hashes.Delete(productId)
+
+	return nil
+}
+
+// mapping возвращает ID товара VK и ID подборки, в которой он лежит, по ID товара МойСклад.
+func (e *Exporter) mapping(productId string) (int64, int64) {
+	entry, ok := e.items.Get(productId)
+	if !ok {
+		return 0, 0
+	}
+
+	item, album, _ := strings.Cut(entry.Value, "/")
+	itemId, _ := strconv.ParseInt(item, 10, 64)
+	albumId, _ := strconv.ParseInt(album, 10, 64)
+
+	return itemId, albumId
+}
+
+// photo возвращает ID фотографии VK для изображения, загружая его при первом использовании.
+func (e *Exporter) photo(ctx context.Context, img storage.Image, main bool) (int64, error) {
+	key := img.Url
+	if main {
+		key = "main:" + key
+	}
+
+	if entry, ok := e.photos.Get(key); ok {
+		if id, err := strconv.ParseInt(entry.Value, 10, 64); err == nil {
+			return id, nil
+		}
+	}
+
+	r, filename, err := openImage(ctx, img)
+	if err != nil {
+		return 0, err
+	}
+
+	defer r.Close()
+
+	id, err := e.api.UploadPhoto(ctx, filename, r, main)
+	if err != nil {
+		return 0, err
+	}
+
+	e.photos.Add(key, strconv.FormatInt(id, 10))
+
+	return id, nil
+}
+
+// openImage открывает скачанный файл изображения, а для внешних изображений (обложка,
+// заглушка) скачивает его по адресу.
+func openImage(ctx context.Context, img storage.Image) (io.ReadCloser, string, error) {
+	if img.Filename != "" {
+		f, err := os.Open(filepath.Join(config.Config.ImagesPath, img.Filename))
+		if err == nil {
+			return f, img.Filename, nil
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, img.Url, nil)
+	if err != nil {
+		return nil, "", err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, "", fmt.Errorf("ошибка скачивания изображения %s: %s", img.Url, resp.Status)
+	}
+
+	return resp.Body, filepath.Base(req.URL.Path), nil
+}
+
+// itemHash вычисляет хеш данных товара, чтобы не изменять неизменившиеся товары.
+func itemHash(item vkapi.Item, productImages []storage.Image, albumId int64) (string, error) {
+	data, err := json.Marshal(struct {
+		Item    vkapi.Item
+		Images  []storage.Image
+		AlbumId int64
+	}{item, productImages, albumId})
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:]), nil
+}
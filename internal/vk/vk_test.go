@@ -0,0 +1,245 @@
+package vk
+
+import (
+	"context"
+	"fmt"
+	"github.com/KirillKhitev/carat_export/internal/config"
+	"github.com/KirillKhitev/carat_export/internal/history"
+	"github.com/KirillKhitev/carat_export/internal/report"
+	"github.com/KirillKhitev/carat_export/internal/storage"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// market заглушка VK Market: хранит подборки и товары, считает вызовы методов.
+type market struct {
+	m      sync.Mutex
+	url    string
+	nextId int64
+	albums map[int64]bool
+	items  map[int64]bool
+	calls  map[string]int
+	// fail методы, которые возвращают ошибку API.
+	fail map[string]bool
+}
+
+func (s *market) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	method := filepath.Base(r.URL.Path)
+	s.calls[method]++
+
+	if s.fail[method] {
+		fmt.Fprint(w, `{"error":{"error_code":1403,"error_msg":"Item not found"}}`)
+		return
+	}
+
+	id := func(name string) int64 {
+		var v int64
+		fmt.Sscan(r.FormValue(name), &v)
+		return v
+	}
+
+	s.nextId++
+
+	switch method {
+	case "market.getAlbums":
+		fmt.Fprint(w, `{"response":{"count":0,"items":[`)
+		sep := ""
+		for albumId := range s.albums {
+			fmt.Fprintf(w, `%s{"id":%d}`, sep, albumId)
+			sep = ","
+		}
+		fmt.Fprint(w, `]}}`)
+	case "market.addAlbum":
+		s.albums[s.nextId] = true
+		fmt.Fprintf(w, `{"response":{"market_album_id":%d}}`, s.nextId)
+	case "market.deleteAlbum":
+		delete(s.albums, id("album_id"))
+		fmt.Fprint(w, `{"response":1}`)
+	case "market.add":
+		s.items[s.nextId] = true
+		fmt.Fprintf(w, `{"response":{"market_item_id":%d}}`, s.nextId)
+	case "market.delete":
+		delete(s.items, id("item_id"))
+		fmt.Fprint(w, `{"response":1}`)
+	case "market.edit", "market.addToAlbum", "market.removeFromAlbum":
+		fmt.Fprint(w, `{"response":1}`)
+	case "photos.getMarketUploadServer":
+		fmt.Fprintf(w, `{"response":{"upload_url":"%s/upload"}}`, s.url)
+	case "upload":
+		fmt.Fprint(w, `{"server":1,"photo":"[]","hash":"h"}`)
+	case "photos.saveMarketPhoto":
+		fmt.Fprintf(w, `{"response":[{"id":%d}]}`, s.nextId)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// newMarket настраивает синхронизацию на заглушку VK Market с пустыми журналами.
+func newMarket(t *testing.T) *market {
+	t.Helper()
+
+	s := &market{
+		albums: make(map[int64]bool),
+		items:  make(map[int64]bool),
+		calls:  make(map[string]int),
+		fail:   make(map[string]bool),
+	}
+
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+	s.url = srv.URL
+
+	old := config.Config
+	t.Cleanup(func() { config.Config = old })
+
+	config.Config.HistoryDir = t.TempDir()
+	config.Config.ImagesPath = t.TempDir()
+	config.Config.Images = config.ImagesParams{}
+	config.Config.VK = config.VKParams{
+		ApiUrl:      srv.URL + "/method/",
+		AccessToken: "token",
+		GroupId:     1,
+		CategoryId:  1,
+	}
+
+	if err := os.WriteFile(filepath.Join(config.Config.ImagesPath, "coin.jpg"), []byte("jpeg"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return s
+}
+
+func product(id string, price int) storage.Product {
+	p := storage.Product{
+		ID:          id,
+		Name:        "Монета " + id,
+		Description: "Серебряная монета, 1 унция",
+		Folder:      "Монеты",
+		Price:       price,
+		Images:      []storage.Image{{Filename: "coin.jpg", Url: "https://example.com/coin.jpg"}},
+	}
+	p.ImagesResponse.Meta.Size = 1
+
+	return p
+}
+
+func syncProducts(t *testing.T, products ...storage.Product) map[string]report.Reason {
+	t.Helper()
+
+	byId := make(map[string]storage.Product, len(products))
+	for _, p := range products {
+		byId[p.ID] = p
+	}
+
+	reasons, err := NewExporter().Sync(context.Background(), byId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return reasons
+}
+
+func TestSyncIsIdempotent(t *testing.T) {
+	s := newMarket(t)
+
+	for i := 0; i < 2; i++ {
+		if reasons := syncProducts(t, product("1", 1500)); reasons["1"] != report.ReasonExported {
+			t.Fatalf("reasons = %v", reasons)
+		}
+	}
+
+	if s.calls["market.add"] != 1 || s.calls["market.edit"] != 0 || s.calls["market.addAlbum"] != 1 {
+		t.Fatalf("calls = %v, want one market.add and no market.edit", s.calls)
+	}
+
+	if s.calls["photos.saveMarketPhoto"] != 1 {
+		t.Fatalf("photo uploaded %d times, want 1", s.calls["photos.saveMarketPhoto"])
+	}
+
+	syncProducts(t, product("1", 2000))
+
+	if s.calls["market.add"] != 1 || s.calls["market.edit"] != 1 {
+		t.Fatalf("calls = %v, want changed item edited", s.calls)
+	}
+}
+
+func TestRemovedItemsAreDeleted(t *testing.T) {
+	s := newMarket(t)
+
+	syncProducts(t, product("1", 1500), product("2", 1500))
+
+	if len(s.items) != 2 {
+		t.Fatalf("items = %v, want 2", s.items)
+	}
+
+	syncProducts(t, product("1", 1500))
+
+	if len(s.items) != 1 || s.calls["market.delete"] != 1 {
+		t.Fatalf("items = %v, calls = %v, want removed item deleted", s.items, s.calls)
+	}
+
+	items, err := history.LoadLedger(ItemsLedgerName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(items.Entries) != 1 || !items.Has("1") {
+		t.Fatalf("ledger = %v, want only product 1", items.Entries)
+	}
+}
+
+func TestCreatedItemIsRecordedWhenAlbumFails(t *testing.T) {
+	s := newMarket(t)
+	s.fail["market.addToAlbum"] = true
+
+	if reasons := syncProducts(t, product("1", 1500)); reasons["1"] != report.ReasonValidationErr {
+		t.Fatalf("reasons = %v, want album error reported", reasons)
+	}
+
+	items, err := history.LoadLedger(ItemsLedgerName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !items.Has("1") {
+		t.Fatal("created item is not recorded in ledger")
+	}
+
+	s.fail["market.addToAlbum"] = false
+
+	syncProducts(t, product("1", 1500))
+
+	if s.calls["market.add"] != 1 || s.calls["market.addToAlbum"] != 2 || len(s.items) != 1 {
+		t.Fatalf("calls = %v, items = %v, want item created once and added to album on retry", s.calls, s.items)
+	}
+}
+
+func TestItemWithoutPriceIsDeleted(t *testing.T) {
+	s := newMarket(t)
+
+	syncProducts(t, product("1", 1500))
+
+	if reasons := syncProducts(t, product("1", 0)); reasons["1"] != report.ReasonNoPrice {
+		t.Fatalf("reasons = %v", reasons)
+	}
+
+	if len(s.items) != 0 || s.calls["market.delete"] != 1 {
+		t.Fatalf("items = %v, calls = %v, want item deleted", s.items, s.calls)
+	}
+
+	items, err := history.LoadLedger(ItemsLedgerName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(items.Entries) != 0 {
+		t.Fatalf("ledger = %v, want empty", items.Entries)
+	}
+}
@@ -0,0 +1,100 @@
+package vkapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/KirillKhitev/carat_export/internal/config"
+	"github.com/go-resty/resty/v2"
+	"strings"
+	"time"
+)
+
+// DefaultURL адрес методов API VK.
+const DefaultURL = "https://api.vk.com/method/"
+
+// Version версия API VK.
+const Version = "5.199"
+
+// Client клиент API VK с ключом доступа сообщества.
+type Client struct {
+	client  *resty.Client
+	token   string
+	groupId int64
+}
+
+// NewClient создает клиент с ключом доступа и сообществом из настроек.
+func NewClient() *Client {
+	return &Client{
+		client:  resty.New(),
+		token:   config.Config.VK.AccessToken,
+		groupId: config.Config.VK.GroupId,
+	}
+}
+
+// APIError ошибка, которую возвращает API VK.
+type APIError struct {
+	Code    int    `json:"error_code"`
+	Message string `json:"error_msg"`
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%d: %s", e.Code, e.Message)
+}
+
+type response struct {
+	Response json.RawMessage `json:"response"`
+	Error    *APIError       `json:"error"`
+}
+
+// methodURL формирует адрес метода API.
+func methodURL(method string) string {
+	base := config.Config.VK.ApiUrl
+	if base == "" {
+		base = DefaultURL
+	}
+
+	return strings.TrimSuffix(base, "/") + "/" + method
+}
+
+// ownerId идентификатор владельца товаров: сообщество со знаком минус.
+func (c *Client) ownerId() string {
+	return fmt.Sprintf("-%d", c.groupId)
+}
+
+// call вызывает метод API и разбирает поле response ответа в result.
+func (c *Client) call(ctx context.Context, method string, params map[string]string, result any) error {
+	contextWithTimeout, cancel := context.WithTimeout(ctx, time.Duration(30*time.Second))
+	defer cancel()
+
+	var body response
+
+	resp, err := c.client.R().
+		SetContext(contextWithTimeout).
+		ForceContentType("application/json").
+		SetFormData(params).
+		SetFormData(map[string]string{
+			"access_token": c.token,
+			"v":            Version,
+		}).
+		SetResult(&body).
+		Post(methodURL(method))
+
+	if err != nil {
+		return err
+	}
+
+	if resp.IsError() {
+		return fmt.Errorf("%s: %s", method, resp.Status())
+	}
+
+	if body.Error != nil {
+		return fmt.Errorf("%s: %w", method, body.Error)
+	}
+
+	if result == nil {
+		return nil
+	}
+
+	return json.Unmarshal(body.Response, result)
+}
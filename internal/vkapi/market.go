@@ -0,0 +1,200 @@
+package vkapi
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Item товар VK Market.
+type Item struct {
+	Name        string
+	Description string
+	CategoryId  int
+	Price       int
+	Sku         string
+	MainPhotoId int64
+	PhotoIds    []int64
+}
+
+func (item Item) params() map[string]string {
+	photoIds := make([]string, 0, len(item.PhotoIds))
+	for _, id := range item.PhotoIds {
+		photoIds = append(photoIds, strconv.FormatInt(id, 10))
+	}
+
+	return map[string]string{
+		"name":          item.Name,
+		"description":   item.Description,
+		"category_id":   strconv.Itoa(item.CategoryId),
+		"price":         strconv.Itoa(item.Price),
+		"sku":           item.Sku,
+		"main_photo_id": strconv.FormatInt(item.MainPhotoId, 10),
+		"photo_ids":     strings.Join(photoIds, ","),
+	}
+}
+
+// AddItem создает товар и возвращает его идентификатор.
+func (c *Client) AddItem(ctx context.Context, item Item) (int64, error) {
+	var result struct {
+		MarketItemId int64 `json:"market_item_id"`
+	}
+
+	params := item.params()
+	params["owner_id"] = c.ownerId()
+
+	if err := c.call(ctx, "market.add", params, &result); err != nil {
+		return 0, err
+	}
+
+	return result.MarketItemId, nil
+}
+
+// EditItem изменяет товар.
+func (c *Client) EditItem(ctx context.Context, itemId int64, item Item) error {
+	params := item.params()
+	params["owner_id"] = c.ownerId()
+	params["item_id"] = strconv.FormatInt(itemId, 10)
+
+	return c.call(ctx, "market.edit", params, nil)
+}
+
+// DeleteItem удаляет товар.
+func (c *Client) DeleteItem(ctx context.Context, itemId int64) error {
+	return c.call(ctx, "market.delete", map[string]string{
+		"owner_id": c.ownerId(),
+		"item_id":  strconv.FormatInt(itemId, 10),
+	}, nil)
+}
+
+// Album подборка товаров.
+type Album struct {
+	Id    int64  `json:"id"`
+	Title string `json:"title"`
+}
+
+// Albums возвращает подборки товаров сообщества.
+func (c *Client) Albums(ctx context.Context) ([]Album, error) {
+	var result struct {
+		Count int     `json:"count"`
+		Items []Album `json:"items"`
+	}
+
+	err := c.call(ctx, "market.getAlbums", map[string]string{
+		"owner_id": c.ownerId(),
+		"count":    "100",
+	}, &result)
+
+	return result.Items, err
+}
+
+// AddAlbum создает подборку и возвращает ее идентификатор.
+func (c *Client) AddAlbum(ctx context.Context, title string) (int64, error) {
+	var result struct {
+		MarketAlbumId int64 `json:"market_album_id"`
+	}
+
+	err := c.call(ctx, "market.addAlbum", map[string]string{
+		"owner_id": c.ownerId(),
+		"title":    title,
+	}, &result)
+
+	return result.MarketAlbumId, err
+}
+
+// DeleteAlbum удаляет подборку. Товары подборки не удаляются.
+func (c *Client) DeleteAlbum(ctx context.Context, albumId int64) error {
+	return c.call(ctx, "market.deleteAlbum", map[string]string{
+		"owner_id": c.ownerId(),
+		"album_id": strconv.FormatInt(albumId, 10),
+	}, nil)
+}
+
+// AddToAlbum добавляет товар в подборку.
+func (c *Client) AddToAlbum(ctx context.Context, itemId int64, albumId int64) error {
+	return c.call(ctx, "market.addToAlbum", map[string]string{
+		"owner_id":  c.ownerId(),
+		"item_ids":  strconv.FormatInt(itemId, 10),
+		"album_ids": strconv.FormatInt(albumId, 10),
+	}, nil)
+}
+
+// RemoveFromAlbum убирает товар из подборки.
+func (c *Client) RemoveFromAlbum(ctx context.Context, itemId int64, albumId int64) error {
+	return c.call(ctx, "market.removeFromAlbum", map[string]string{
+		"owner_id":  c.ownerId(),
+		"item_id":   strconv.FormatInt(itemId, 10),
+		"album_ids": strconv.FormatInt(albumId, 10),
+	}, nil)
+}
+
+type uploadResponse struct {
+	Server    int64  `json:"server"`
+	Photo     string `json:"photo"`
+	Hash      string `json:"hash"`
+	CropData  string `json:"crop_data"`
+	CropHash  string `json:"crop_hash"`
+	ErrorText string `json:"error"`
+}
+
+// UploadPhoto загружает фотографию товара и возвращает ее идентификатор.
+func (c *Client) UploadPhoto(ctx context.Context, filename string, r io.Reader, main bool) (int64, error) {
+	var server struct {
+		UploadUrl string `json:"upload_url"`
+	}
+
+	params := map[string]string{"group_id": strconv.FormatInt(c.groupId, 10)}
+	if main {
+		params["main_photo"] = "1"
+	}
+
+	if err := c.call(ctx, "photos.getMarketUploadServer", params, &server); err != nil {
+		return 0, err
+	}
+
+	contextWithTimeout, cancel := context.WithTimeout(ctx, time.Duration(60*time.Second))
+	defer cancel()
+
+	var uploaded uploadResponse
+
+	resp, err := c.client.R().
+		SetContext(contextWithTimeout).
+		ForceContentType("application/json").
+		SetFileReader("file", filename, r).
+		SetResult(&uploaded).
+		Post(server.UploadUrl)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if resp.IsError() || uploaded.ErrorText != "" {
+		return 0, fmt.Errorf("ошибка загрузки фото %s: %s %s", filename, resp.Status(), uploaded.ErrorText)
+	}
+
+	var saved []struct {
+		Id int64 `json:"id"`
+	}
+
+	err = c.call(ctx, "photos.saveMarketPhoto", map[string]string{
+		"group_id":  strconv.FormatInt(c.groupId, 10),
+		"server":    strconv.FormatInt(uploaded.Server, 10),
+		"photo":     uploaded.Photo,
+		"hash":      uploaded.Hash,
+		"crop_data": uploaded.CropData,
+		"crop_hash": uploaded.CropHash,
+	}, &saved)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if len(saved) == 0 {
+		return 0, fmt.Errorf("photos.saveMarketPhoto: пустой ответ")
+	}
+
+	return saved[0].Id, nil
+}
@@ -0,0 +1,134 @@
+package wbapi
+
+import (
+	"context"
+	"fmt"
+	"github.com/KirillKhitev/carat_export/internal/config"
+	"github.com/go-resty/resty/v2"
+	"strings"
+	"time"
+)
+
+// Адреса API Wildberries: маркетплейс (остатки) и цены со скидками.
+const (
+	DefaultMarketplaceURL = "https://marketplace-api.wildberries.ru/"
+	DefaultPricesURL      = "https://discounts-prices-api.wildberries.ru/"
+)
+
+// Лимиты количества товаров в одном запросе.
+const (
+	MaxStockItems = 1000
+	MaxPriceItems = 1000
+)
+
+// Client клиент API Wildberries с авторизацией по токену.
+type Client struct {
+	client *resty.Client
+	token  string
+}
+
+// NewClient создает клиент с токеном из настроек.
+func NewClient() *Client {
+	return &Client{
+		client: resty.New(),
+		token:  config.Config.Wildberries.ApiToken,
+	}
+}
+
+// APIError ошибка, которую возвращает API Wildberries.
+type APIError struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	ErrorText string `json:"errorText"`
+}
+
+func (e *APIError) Error() string {
+	if e.ErrorText != "" {
+		return e.ErrorText
+	}
+
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+// methodURL формирует адрес метода API относительно base или адреса по умолчанию.
+func methodURL(base, defaultBase, path string) string {
+	if base == "" {
+		base = defaultBase
+	}
+
+	return strings.TrimSuffix(base, "/") + "/" + strings.TrimPrefix(path, "/")
+}
+
+// request выполняет запрос к API Wildberries и разбирает ответ в result.
+func (c *Client) request(ctx context.Context, method, url string, body any, result any) error {
+	contextWithTimeout, cancel := context.WithTimeout(ctx, time.Duration(30*time.Second))
+	defer cancel()
+
+	var responseErr APIError
+
+	req := c.client.R().
+		SetContext(contextWithTimeout).
+		ForceContentType("application/json").
+		SetHeader("Authorization", c.token).
+		SetBody(body).
+		SetError(&responseErr)
+
+	if result != nil {
+		req.SetResult(result)
+	}
+
+	response, err := req.Execute(method, url)
+	if err != nil {
+		return err
+	}
+
+	if response.IsError() {
+		return fmt.Errorf("%s %s: %s: %w", method, url, response.Status(), &responseErr)
+	}
+
+	return nil
+}
+
+// Stock остаток товара по баркоду.
+type Stock struct {
+	Sku    string `json:"sku"`
+	Amount int    `json:"amount"`
+}
+
+// UpdateStocks обновляет остатки товаров на складе продавца.
+func (c *Client) UpdateStocks(ctx context.Context, warehouseId int64, stocks []Stock) error {
+	url := methodURL(config.Config.Wildberries.MarketplaceApiUrl, DefaultMarketplaceURL, fmt.Sprintf("api/v3/stocks/%d", warehouseId))
+
+	return c.request(ctx, "PUT", url, map[string]any{"stocks": stocks}, nil)
+}
+
+// Price цена и скидка товара по nmID.
+type Price struct {
+	NmId     int64 `json:"nmID"`
+	Price    int   `json:"price"`
+	Discount int   `json:"discount"`
+}
+
+type uploadTaskResponse struct {
+	Data struct {
+		Id int64 `json:"id"`
+	} `json:"data"`
+	Error     bool   `json:"error"`
+	ErrorText string `json:"errorText"`
+}
+
+// UpdatePrices загружает цены и скидки товаров и возвращает идентификатор задачи загрузки.
+func (c *Client) UpdatePrices(ctx context.Context, prices []Price) (int64, error) {
+	var result uploadTaskResponse
+
+	url := methodURL(config.Config.Wildberries.PricesApiUrl, DefaultPricesURL, "api/v2/upload/task")
+	if err := c.request(ctx, "POST", url, map[string]any{"data": prices}, &result); err != nil {
+		return 0, err
+	}
+
+	if result.Error {
+		return 0, &APIError{ErrorText: result.ErrorText}
+	}
+
+	return result.Data.Id, nil
+}
@@ -0,0 +1,260 @@
+package wildberries
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/KirillKhitev/carat_export/internal/config"
+	"github.com/KirillKhitev/carat_export/internal/fileutil"
+	"github.com/KirillKhitev/carat_export/internal/history"
+	"github.com/KirillKhitev/carat_export/internal/logger"
+	"github.com/KirillKhitev/carat_export/internal/report"
+	"github.com/KirillKhitev/carat_export/internal/storage"
+	"github.com/KirillKhitev/carat_export/internal/wbapi"
+	"github.com/sirupsen/logrus"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StocksLedgerName журнал товаров, остатки которых отправлены в WB: nmID -> баркод. Если товар
+// пропадает из МойСклад или теряет nmID, его остаток обнуляется.
+const StocksLedgerName = "wildberries_stocks"
+
+// UnmatchedFileName файл со списком товаров, которые не удалось сопоставить с карточками WB.
+const UnmatchedFileName = "wildberries_unmatched.json"
+
+// Unmatched товар МойСклад без nmID или баркода Wildberries.
+type Unmatched struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Article string `json:"article"`
+	Reason  string `json:"reason"`
+}
+
+// UnmatchedReport список несопоставленных товаров за последний запуск.
+type UnmatchedReport struct {
+	CreatedAt time.Time   `json:"created_at"`
+	Items     []Unmatched `json:"items"`
+}
+
+func init() {
+	config.ReserveRoute("/"+UnmatchedFileName, "товары без карточек Wildberries")
+}
+
+// UnmatchedPath путь до файла несопоставленных товаров.
+func UnmatchedPath() string {
+	return filepath.Join(config.Config.ReportDir, UnmatchedFileName)
+}
+
+// Exporter обновляет цены и остатки товаров в Wildberries по данным МойСклад.
+type Exporter struct {
+	api *wbapi.Client
+}
+
+func NewExporter() *Exporter {
+	return &Exporter{
+		api: wbapi.NewClient(),
+	}
+}
+
+// Enabled показывает, настроена ли синхронизация с Wildberries.
+func Enabled() bool {
+	return config.Config.Wildberries.Enabled()
+}
+
+// Sync отправляет остатки по складам WB и цены сопоставленных товаров, а выгружаемые в Avito
+// товары без nmID или баркода сохраняет в отчет. products - все товары МойСклад, включая
+// исключенные из выгрузки Avito, чтобы закончившиеся товары получили нулевой остаток. Если
+// остатки по складам не получены (storesLoaded), остатки в WB не меняются.
+// Возвращает результат по каждому выгружаемому в Avito товару: ID товара -> причина.
+func (e *Exporter) Sync(ctx context.Context, products map[string]storage.Product, storesLoaded bool) (map[string]report.Reason, error) {
+	params := config.Config.Wildberries
+
+	ledger, err := history.LoadLedger(StocksLedgerName)
+	if err != nil {
+		return nil, err
+	}
+
+	productReasons := make(map[string]report.Reason, len(products))
+
+	prices := make([]wbapi.Price, 0, len(products))
+	skus := make(map[string]string, len(products))
+	unmatched := UnmatchedReport{CreatedAt: time.Now(), Items: make([]Unmatched, 0)}
+
+	for _, p := range products {
+		reasons := make([]string, 0, 2)
+
+		nmId, err := strconv.ParseInt(strings.TrimSpace(p.Attributes[params.NmIdAttribute]), 10, 64)
+		if err != nil || nmId <= 0 {
+			reasons = append(reasons, "не заполнен nmID")
+		}
+
+		sku := barcode(p)
+		if sku == "" {
+			reasons = append(reasons, "не заполнен баркод")
+		}
+
+		if len(reasons) > 0 {
+			if p.ExportAvito {
+				unmatched.Items = append(unmatched.Items, Unmatched{
+					ID:      p.ID,
+					Name:    p.Name,
+					Article: p.Article,
+					Reason:  strings.Join(reasons, ", "),
+				})
+
+				productReasons[p.ID] = report.ReasonNotMatched
+			}
+
+			continue
+		}
+
+		if p.ExportAvito {
+			productReasons[p.ID] = report.ReasonExported
+		}
+
+		if price := p.PriceByType(params.PriceType); price > 0 {
+			prices = append(prices, wbapi.Price{NmId: nmId, Price: price, Discount: params.Discount})
+		}
+
+		skus[strconv.FormatInt(nmId, 10)] = sku
+	}
+
+	if storesLoaded {
+		e.syncStocks(ctx, products, skus, ledger)
+	} else {
+		logger.Log.Log(logrus.WarnLevel, "Остатки по складам не получены, остатки Wildberries не обновляем")
+	}
+
+	if err := ledger.Save(); err != nil {
+		return nil, err
+	}
+
+	if err := e.updatePrices(ctx, prices); err != nil {
+		logger.Log.WithFields(logrus.Fields{
+			"error": err,
+		}).Log(logrus.ErrorLevel, "Ошибка при обновлении цен Wildberries")
+	}
+
+	if len(unmatched.Items) > 0 {
+		logger.Log.Logf(logrus.WarnLevel, "Не сопоставлено с карточками Wildberries товаров: %d", len(unmatched.Items))
+	}
+
+	return productReasons, unmatched.Save(UnmatchedPath())
+}
+
+// syncStocks отправляет остатки сопоставленных товаров (skus: nmID -> баркод) и нулевые остатки
+// товаров из журнала, которые больше не сопоставлены. Журнал обновляется, только если остатки
+// отправлены на все склады.
+func (e *Exporter) syncStocks(ctx context.Context, products map[string]storage.Product, skus map[string]string, ledger *history.Ledger) {
+	params := config.Config.Wildberries
+	stores := make(map[string]map[string]float32, len(skus))
+
+	for _, p := range products {
+		if nmId := strings.TrimSpace(p.Attributes[params.NmIdAttribute]); skus[nmId] != "" {
+			stores[skus[nmId]] = p.Stores
+		}
+	}
+
+	// Обнуляем остатки баркодов из журнала, которые больше не относятся к сопоставленным товарам.
+	removed := make([]string, 0)
+	for nmId, entry := range ledger.Entries {
+		if _, ok := skus[nmId]; !ok {
+			removed = append(removed, nmId)
+		}
+
+		if _, ok := stores[entry.Value]; !ok {
+			stores[entry.Value] = nil
+		}
+	}
+
+	stocks := make(map[int64][]wbapi.Stock, len(params.Warehouses))
+
+	for sku, productStores := range stores {
+		for store, warehouseId := range params.Warehouses {
+			stocks[warehouseId] = append(stocks[warehouseId], wbapi.Stock{
+				Sku:    sku,
+				Amount: max(int(productStores[store]), 0),
+			})
+		}
+	}
+
+	ok := true
+
+	for warehouseId, warehouseStocks := range stocks {
+		if err := e.updateStocks(ctx, warehouseId, warehouseStocks); err != nil {
+			ok = false
+
+			logger.Log.WithFields(logrus.Fields{
+				"error":       err,
+				"warehouseId": warehouseId,
+			}).Log(logrus.ErrorLevel, "Ошибка при обновлении остатков Wildberries")
+		}
+	}
+
+	if !ok {
+		return
+	}
+
+	for _, nmId := range removed {
+		ledger.Delete(nmId)
+	}
+
+	for nmId, sku := range skus {
+		ledger.Add(nmId, sku)
+	}
+}
+
+func (e *Exporter) updateStocks(ctx context.Context, warehouseId int64, stocks []wbapi.Stock) error {
+	for start := 0; start < len(stocks); start += wbapi.MaxStockItems {
+		if err := e.api.UpdateStocks(ctx, warehouseId, stocks[start:min(start+wbapi.MaxStockItems, len(stocks))]); err != nil {
+			return err
+		}
+	}
+
+	logger.Log.Logf(logrus.InfoLevel, "Обновили остатки Wildberries на складе %d: %d", warehouseId, len(stocks))
+
+	return nil
+}
+
+func (e *Exporter) updatePrices(ctx context.Context, prices []wbapi.Price) error {
+	for start := 0; start < len(prices); start += wbapi.MaxPriceItems {
+		taskId, err := e.api.UpdatePrices(ctx, prices[start:min(start+wbapi.MaxPriceItems, len(prices))])
+		if err != nil {
+			return err
+		}
+
+		logger.Log.Logf(logrus.InfoLevel, "Отправили цены Wildberries, задача %d", taskId)
+	}
+
+	return nil
+}
+
+// barcode возвращает баркод WB из доп. поля, а если оно не заполнено - штрихкод товара МойСклад.
+func barcode(p storage.Product) string {
+	if sku := strings.TrimSpace(p.Attributes[config.Config.Wildberries.BarcodeAttribute]); sku != "" {
+		return sku
+	}
+
+	return p.Barcode()
+}
+
+// Save сохраняет список несопоставленных товаров в JSON файл.
+func (r UnmatchedReport) Save(path string) error {
+	sort.Slice(r.Items, func(i, j int) bool {
+		return r.Items[i].Name < r.Items[j].Name
+	})
+
+	data, err := json.MarshalIndent(r, "", "   ")
+	if err != nil {
+		return err
+	}
+
+	return fileutil.WriteAtomic(path, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
@@ -0,0 +1,222 @@
+package wildberries
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/KirillKhitev/carat_export/internal/config"
+	"github.com/KirillKhitev/carat_export/internal/history"
+	"github.com/KirillKhitev/carat_export/internal/report"
+	"github.com/KirillKhitev/carat_export/internal/storage"
+	"github.com/KirillKhitev/carat_export/internal/wbapi"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// marketplace заглушка API Wildberries: запоминает остатки по складам и отправленные цены.
+type marketplace struct {
+	m      sync.Mutex
+	stocks map[string]map[string]int
+	prices []wbapi.Price
+}
+
+func (s *marketplace) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if r.Header.Get("Authorization") != "token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodPut && strings.HasPrefix(r.URL.Path, "/api/v3/stocks/"):
+		var body struct {
+			Stocks []wbapi.Stock `json:"stocks"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+
+		warehouse := strings.TrimPrefix(r.URL.Path, "/api/v3/stocks/")
+		if s.stocks[warehouse] == nil {
+			s.stocks[warehouse] = make(map[string]int)
+		}
+
+		for _, stock := range body.Stocks {
+			s.stocks[warehouse][stock.Sku] = stock.Amount
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	case r.URL.Path == "/api/v2/upload/task":
+		var body struct {
+			Data []wbapi.Price `json:"data"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+
+		s.prices = append(s.prices, body.Data...)
+		fmt.Fprint(w, `{"data":{"id":1},"error":false}`)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// newMarketplace настраивает синхронизацию на заглушку WB: склад МойСклад «Основной» -> склад WB 507.
+func newMarketplace(t *testing.T) *marketplace {
+	t.Helper()
+
+	s := &marketplace{stocks: make(map[string]map[string]int)}
+
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+
+	old := config.Config
+	t.Cleanup(func() { config.Config = old })
+
+	config.Config.HistoryDir = t.TempDir()
+	config.Config.ReportDir = t.TempDir()
+	config.Config.Wildberries = config.WildberriesParams{
+		ApiToken:          "token",
+		MarketplaceApiUrl: srv.URL,
+		PricesApiUrl:      srv.URL,
+		NmIdAttribute:     "nmID",
+		Discount:          10,
+		Warehouses:        map[string]int64{"Основной": 507},
+	}
+
+	return s
+}
+
+func coin(id string, nmId string, stock float32) storage.Product {
+	return storage.Product{
+		ID:          id,
+		Name:        "Монета " + id,
+		Price:       1500,
+		Stores:      map[string]float32{"Основной": stock},
+		Attributes:  map[string]string{"nmID": nmId},
+		Barcodes:    []map[string]string{{"ean13": "460" + id}},
+		ExportAvito: true,
+	}
+}
+
+func syncProducts(t *testing.T, products ...storage.Product) map[string]report.Reason {
+	t.Helper()
+
+	return syncWithStores(t, true, products...)
+}
+
+func syncWithStores(t *testing.T, storesLoaded bool, products ...storage.Product) map[string]report.Reason {
+	t.Helper()
+
+	byId := make(map[string]storage.Product, len(products))
+	for _, p := range products {
+		byId[p.ID] = p
+	}
+
+	reasons, err := NewExporter().Sync(context.Background(), byId, storesLoaded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return reasons
+}
+
+func TestSyncStocksAndPrices(t *testing.T) {
+	s := newMarketplace(t)
+
+	syncProducts(t, coin("1", "101", 3), coin("2", "", -1))
+
+	if got := s.stocks["507"]; len(got) != 1 || got["4601"] != 3 {
+		t.Fatalf("stocks = %v, want only matched 4601: 3", s.stocks)
+	}
+
+	if len(s.prices) != 1 || s.prices[0] != (wbapi.Price{NmId: 101, Price: 1500, Discount: 10}) {
+		t.Fatalf("prices = %+v, want only product with nmID", s.prices)
+	}
+
+	data, err := os.ReadFile(UnmatchedPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var unmatched UnmatchedReport
+	if err := json.Unmarshal(data, &unmatched); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(unmatched.Items) != 1 || unmatched.Items[0].ID != "2" || unmatched.Items[0].Reason != "не заполнен nmID" {
+		t.Fatalf("unmatched = %+v", unmatched.Items)
+	}
+}
+
+func TestDroppedCardsGetZeroStock(t *testing.T) {
+	s := newMarketplace(t)
+
+	syncProducts(t, coin("1", "101", 3), coin("3", "103", 5))
+	syncProducts(t, coin("1", "101", 2))
+
+	if got := s.stocks["507"]; got["4601"] != 2 || got["4603"] != 0 {
+		t.Fatalf("stocks = %v, want 4601: 2, 4603: 0", s.stocks)
+	}
+
+	ledger, err := history.LoadLedger(StocksLedgerName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ledger.Entries) != 1 || !ledger.Has("101") {
+		t.Fatalf("ledger = %v, want only nmID 101", ledger.Entries)
+	}
+}
+
+func TestStocksAreSkippedWhenStoresFailed(t *testing.T) {
+	s := newMarketplace(t)
+
+	syncWithStores(t, false, coin("1", "101", 3))
+
+	if len(s.stocks) != 0 {
+		t.Fatalf("stocks = %v, want none without store stocks", s.stocks)
+	}
+
+	if len(s.prices) != 1 {
+		t.Fatalf("prices = %+v, want prices sent anyway", s.prices)
+	}
+
+	ledger, err := history.LoadLedger(StocksLedgerName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ledger.Entries) != 0 {
+		t.Fatalf("ledger = %v, want empty", ledger.Entries)
+	}
+}
+
+func TestReasonsOnlyForAvitoProducts(t *testing.T) {
+	newMarketplace(t)
+
+	hidden := coin("3", "103", 1)
+	hidden.ExportAvito = false
+
+	hiddenUnmatched := coin("4", "", 1)
+	hiddenUnmatched.ExportAvito = false
+
+	reasons := syncProducts(t, coin("1", "101", 3), coin("2", "", 1), hidden, hiddenUnmatched)
+
+	want := map[string]report.Reason{
+		"1": report.ReasonExported,
+		"2": report.ReasonNotMatched,
+	}
+
+	if len(reasons) != len(want) {
+		t.Fatalf("reasons = %v, want %v", reasons, want)
+	}
+
+	for id, reason := range want {
+		if reasons[id] != reason {
+			t.Errorf("reasons[%s] = %s, want %s", id, reasons[id], reason)
+		}
+	}
+}
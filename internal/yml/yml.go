@@ -0,0 +1,148 @@
+package yml
+
+import (
+	"encoding/xml"
+	"github.com/KirillKhitev/carat_export/internal/fileutil"
+	"github.com/KirillKhitev/carat_export/internal/logger"
+	"github.com/KirillKhitev/carat_export/internal/timeutil"
+	"github.com/sirupsen/logrus"
+	"hash/crc32"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DateFormat формат даты каталога YML.
+const DateFormat = "2006-01-02T15:04:05-07:00"
+
+// CurrencyRUB валюта цен каталога.
+const CurrencyRUB = "RUB"
+
+// Catalog корневой элемент YML каталога Яндекс Маркета.
+type Catalog struct {
+	XMLName xml.Name `xml:"yml_catalog"`
+	Date    string   `xml:"date,attr"`
+	Shop    Shop     `xml:"shop"`
+}
+
+type Shop struct {
+	Name       string     `xml:"name"`
+	Company    string     `xml:"company"`
+	URL        string     `xml:"url"`
+	Currencies []Currency `xml:"currencies>currency"`
+	Categories []Category `xml:"categories>category"`
+	Offers     []Offer    `xml:"offers>offer"`
+}
+
+type Currency struct {
+	ID   string `xml:"id,attr"`
+	Rate string `xml:"rate,attr"`
+}
+
+type Category struct {
+	ID       string `xml:"id,attr"`
+	ParentID string `xml:"parentId,attr,omitempty"`
+	Name     string `xml:",chardata"`
+}
+
+type Offer struct {
+	ID          string      `xml:"id,attr"`
+	Available   bool        `xml:"available,attr"`
+	Name        string      `xml:"name"`
+	Price       int         `xml:"price"`
+	CurrencyID  string      `xml:"currencyId"`
+	CategoryID  string      `xml:"categoryId,omitempty"`
+	Pictures    []string    `xml:"picture"`
+	Description Description `xml:"description"`
+	VendorCode  string      `xml:"vendorCode,omitempty"`
+	Barcode     string      `xml:"barcode,omitempty"`
+	Params      []Param     `xml:"param"`
+}
+
+type Description struct {
+	Text string `xml:",cdata"`
+}
+
+type Param struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:",chardata"`
+}
+
+// NewCatalog создает каталог магазина с ценами в рублях.
+func NewCatalog(name, company, url string, now time.Time) Catalog {
+	return Catalog{
+		Date: now.In(timeutil.Moscow).Format(DateFormat),
+		Shop: Shop{
+			Name:       name,
+			Company:    company,
+			URL:        url,
+			Currencies: []Currency{{ID: CurrencyRUB, Rate: "1"}},
+		},
+	}
+}
+
+// CategoryID возвращает идентификатор категории по пути группы МойСклад. Идентификатор
+// вычисляется из пути, поэтому не меняется между выгрузками.
+func CategoryID(folder string) string {
+	return strconv.FormatUint(uint64(crc32.ChecksumIEEE([]byte(folder))), 10)
+}
+
+// Categories строит дерево категорий по путям групп МойСклад вида "Монеты/Россия",
+// добавляя недостающие родительские группы.
+func Categories(folders []string) []Category {
+	paths := make(map[string]struct{})
+
+	for _, folder := range folders {
+		parts := strings.Split(folder, "/")
+
+		for i := range parts {
+			if path := strings.Join(parts[:i+1], "/"); path != "" {
+				paths[path] = struct{}{}
+			}
+		}
+	}
+
+	sorted := make([]string, 0, len(paths))
+	for path := range paths {
+		sorted = append(sorted, path)
+	}
+
+	sort.Strings(sorted)
+
+	result := make([]Category, 0, len(sorted))
+
+	for _, path := range sorted {
+		category := Category{ID: CategoryID(path), Name: path}
+
+		if i := strings.LastIndex(path, "/"); i >= 0 {
+			category.ParentID = CategoryID(path[:i])
+			category.Name = path[i+1:]
+		}
+
+		result = append(result, category)
+	}
+
+	return result
+}
+
+// CreateFile атомарно сохраняет каталог в файл.
+func CreateFile(path string, catalog Catalog) error {
+	logger.Log.Logf(logrus.InfoLevel, "Сохраняем товары в файл Яндекс Маркета %s", path)
+
+	return fileutil.WriteAtomic(path, func(w io.Writer) error {
+		if _, err := io.WriteString(w, xml.Header); err != nil {
+			return err
+		}
+
+		enc := xml.NewEncoder(w)
+		enc.Indent("", "   ")
+
+		if err := enc.Encode(catalog); err != nil {
+			return err
+		}
+
+		return enc.Flush()
+	})
+}
@@ -0,0 +1,81 @@
+package yml
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCategories(t *testing.T) {
+	category := func(path, parent, name string) Category {
+		c := Category{ID: CategoryID(path), Name: name}
+		if parent != "" {
+			c.ParentID = CategoryID(parent)
+		}
+
+		return c
+	}
+
+	tests := []struct {
+		name    string
+		folders []string
+		want    []Category
+	}{
+		{
+			name:    "no folders",
+			folders: nil,
+			want:    []Category{},
+		},
+		{
+			name:    "root folder",
+			folders: []string{"Монеты"},
+			want:    []Category{category("Монеты", "", "Монеты")},
+		},
+		{
+			name:    "parents are added",
+			folders: []string{"Монеты/Россия/Серебро"},
+			want: []Category{
+				category("Монеты", "", "Монеты"),
+				category("Монеты/Россия", "Монеты", "Россия"),
+				category("Монеты/Россия/Серебро", "Монеты/Россия", "Серебро"),
+			},
+		},
+		{
+			name:    "shared parents and duplicates",
+			folders: []string{"Монеты/Россия", "Монеты/Европа", "Монеты/Россия", "Банкноты"},
+			want: []Category{
+				category("Банкноты", "", "Банкноты"),
+				category("Монеты", "", "Монеты"),
+				category("Монеты/Европа", "Монеты", "Европа"),
+				category("Монеты/Россия", "Монеты", "Россия"),
+			},
+		},
+		{
+			name:    "same name under different parents",
+			folders: []string{"Монеты/Серебро", "Слитки/Серебро"},
+			want: []Category{
+				category("Монеты", "", "Монеты"),
+				category("Монеты/Серебро", "Монеты", "Серебро"),
+				category("Слитки", "", "Слитки"),
+				category("Слитки/Серебро", "Слитки", "Серебро"),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Categories(tt.folders); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Categories(%v) = %+v, want %+v", tt.folders, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCategoryIDIsStable(t *testing.T) {
+	if CategoryID("Монеты/Серебро") != CategoryID("Монеты/Серебро") {
+		t.Fatal("category id changes between calls")
+	}
+
+	if CategoryID("Монеты/Серебро") == CategoryID("Слитки/Серебро") {
+		t.Fatal("different folders have the same category id")
+	}
+}